@@ -3,12 +3,15 @@ package ast
 import (
 	"bytes"
 	"interpreter-go/token"
+	"math/big"
 	"strings"
 )
 
 type Node interface {
 	String() string
 	TokenLiteral() string
+	Pos() token.Position //ノードの最初の文字の位置
+	End() token.Position //ノードの直後の位置
 }
 
 type Statement interface {
@@ -32,6 +35,20 @@ func (ls LetStatementNode) TokenLiteral() string {
 	return ls.Token.Literal
 }
 
+func (ls LetStatementNode) Pos() token.Position {
+	return ls.Token.Pos
+}
+
+func (ls LetStatementNode) End() token.Position {
+	if ls.Value != nil {
+		return ls.Value.End()
+	}
+	if ls.Name != nil {
+		return ls.Name.End()
+	}
+	return ls.Token.End
+}
+
 func (ls LetStatementNode) StatementNode() {}
 
 func (ls LetStatementNode) String() string {
@@ -56,6 +73,14 @@ func (i Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i Identifier) Pos() token.Position {
+	return i.Token.Pos
+}
+
+func (i Identifier) End() token.Position {
+	return i.Token.End
+}
+
 func (i Identifier) ExpressionNode() {}
 
 func (i Identifier) String() string {
@@ -79,6 +104,20 @@ func (p Program) String() string {
 
 func (p Program) TokenLiteral() string { return "" }
 
+func (p Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
+func (p Program) End() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+	return token.Position{}
+}
+
 //return <expression>;
 type ReturnStatementNode struct {
 	Token       token.Token
@@ -86,7 +125,18 @@ type ReturnStatementNode struct {
 }
 
 func (rs ReturnStatementNode) TokenLiteral() string {
-	return rs.TokenLiteral()
+	return rs.Token.Literal
+}
+
+func (rs ReturnStatementNode) Pos() token.Position {
+	return rs.Token.Pos
+}
+
+func (rs ReturnStatementNode) End() token.Position {
+	if rs.ReturnValue != nil {
+		return rs.ReturnValue.End()
+	}
+	return rs.Token.End
 }
 
 func (rs ReturnStatementNode) StatementNode() {}
@@ -114,6 +164,17 @@ func (es ExpressionStatement) TokenLiteral() string {
 	return es.Token.Literal
 }
 
+func (es ExpressionStatement) Pos() token.Position {
+	return es.Token.Pos
+}
+
+func (es ExpressionStatement) End() token.Position {
+	if es.Expression != nil {
+		return es.Expression.End()
+	}
+	return es.Token.End
+}
+
 func (es ExpressionStatement) StatementNode() {}
 
 func (es ExpressionStatement) String() string {
@@ -126,18 +187,73 @@ func (es ExpressionStatement) String() string {
 type IntegerLiteral struct {
 	Token token.Token
 	Value int64
+	Big   *big.Int //int64に収まらないときだけ設定する
 }
 
 func (il IntegerLiteral) TokenLiteral() string {
 	return il.Token.Literal
 }
 
+func (il IntegerLiteral) Pos() token.Position {
+	return il.Token.Pos
+}
+
+func (il IntegerLiteral) End() token.Position {
+	return il.Token.End
+}
+
 func (il IntegerLiteral) ExpressionNode() {}
 
 func (il IntegerLiteral) String() string {
 	return il.TokenLiteral()
 }
 
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl FloatLiteral) TokenLiteral() string {
+	return fl.Token.Literal
+}
+
+func (fl FloatLiteral) Pos() token.Position {
+	return fl.Token.Pos
+}
+
+func (fl FloatLiteral) End() token.Position {
+	return fl.Token.End
+}
+
+func (fl FloatLiteral) ExpressionNode() {}
+
+func (fl FloatLiteral) String() string {
+	return fl.TokenLiteral()
+}
+
+type StringLiteral struct {
+	Token token.Token
+	Value string
+}
+
+func (sl StringLiteral) TokenLiteral() string {
+	return sl.Token.Literal
+}
+
+func (sl StringLiteral) Pos() token.Position {
+	return sl.Token.Pos
+}
+
+func (sl StringLiteral) End() token.Position {
+	return sl.Token.End
+}
+
+func (sl StringLiteral) ExpressionNode() {}
+
+func (sl StringLiteral) String() string {
+	return sl.TokenLiteral()
+}
+
 //<prefix operator> <Expression>;
 type PrefixExpression struct {
 	Token    token.Token
@@ -149,6 +265,17 @@ func (pe PrefixExpression) TokenLiteral() string {
 	return pe.Token.Literal
 }
 
+func (pe PrefixExpression) Pos() token.Position {
+	return pe.Token.Pos
+}
+
+func (pe PrefixExpression) End() token.Position {
+	if pe.Right != nil {
+		return pe.Right.End()
+	}
+	return pe.Token.End
+}
+
 func (pe PrefixExpression) ExpressionNode() {}
 
 //本の中ではbytes.bufferにwriteStringで書き込んでいる
@@ -171,6 +298,20 @@ func (pe InfixExpression) TokenLiteral() string {
 	return pe.Token.Literal
 }
 
+func (pe InfixExpression) Pos() token.Position {
+	if pe.Left != nil {
+		return pe.Left.Pos()
+	}
+	return pe.Token.Pos
+}
+
+func (pe InfixExpression) End() token.Position {
+	if pe.Right != nil {
+		return pe.Right.End()
+	}
+	return pe.Token.End
+}
+
 func (pe InfixExpression) ExpressionNode() {}
 
 //本の中ではbytes.bufferにwriteStringで書き込んでいる
@@ -190,6 +331,14 @@ func (b Boolean) TokenLiteral() string {
 	return b.Token.Literal
 }
 
+func (b Boolean) Pos() token.Position {
+	return b.Token.Pos
+}
+
+func (b Boolean) End() token.Position {
+	return b.Token.End
+}
+
 func (b Boolean) ExpressionNode() {}
 
 func (b Boolean) String() string {
@@ -207,6 +356,20 @@ func (ie IfExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
 
+func (ie IfExpression) Pos() token.Position {
+	return ie.Token.Pos
+}
+
+func (ie IfExpression) End() token.Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+	if ie.Consequence != nil {
+		return ie.Consequence.End()
+	}
+	return ie.Token.End
+}
+
 func (ie IfExpression) ExpressionNode() {}
 
 func (ie IfExpression) String() string {
@@ -227,12 +390,27 @@ func (ie IfExpression) String() string {
 type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
+	Rbrace     token.Token
 }
 
 func (bs BlockStatement) TokenLiteral() string {
 	return bs.Token.Literal
 }
 
+func (bs BlockStatement) Pos() token.Position {
+	return bs.Token.Pos
+}
+
+func (bs BlockStatement) End() token.Position {
+	if bs.Rbrace.End.IsValid() {
+		return bs.Rbrace.End
+	}
+	if len(bs.Statements) > 0 {
+		return bs.Statements[len(bs.Statements)-1].End()
+	}
+	return bs.Token.End
+}
+
 func (bs BlockStatement) StatementNode() {}
 
 func (bs BlockStatement) String() string {
@@ -250,12 +428,24 @@ type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
 	Body       *BlockStatement
+	Name       string //letで束縛された場合の名前。コンパイラが再帰呼び出しに使う
 }
 
 func (fl FunctionLiteral) TokenLiteral() string {
 	return fl.Token.Literal
 }
 
+func (fl FunctionLiteral) Pos() token.Position {
+	return fl.Token.Pos
+}
+
+func (fl FunctionLiteral) End() token.Position {
+	if fl.Body != nil {
+		return fl.Body.End()
+	}
+	return fl.Token.End
+}
+
 func (fl FunctionLiteral) ExpressionNode() {}
 
 func (fl FunctionLiteral) String() string {
@@ -282,12 +472,27 @@ type CallExpression struct {
 	Token     token.Token
 	Function  Expression //identifier or functionLiteral
 	Arguments []Expression
+	Rparen    token.Token
 }
 
 func (ce CallExpression) TokenLiteral() string {
 	return ce.Token.Literal
 }
 
+func (ce CallExpression) Pos() token.Position {
+	if ce.Function != nil {
+		return ce.Function.Pos()
+	}
+	return ce.Token.Pos
+}
+
+func (ce CallExpression) End() token.Position {
+	if ce.Rparen.End.IsValid() {
+		return ce.Rparen.End
+	}
+	return ce.Token.End
+}
+
 func (ce CallExpression) ExpressionNode() {}
 
 func (ce CallExpression) String() string {
@@ -304,3 +509,320 @@ func (ce CallExpression) String() string {
 	return out.String()
 
 }
+
+//[<expression>, <expression>, ...]
+type ArrayLiteral struct {
+	Token    token.Token
+	Elements []Expression
+	Rbracket token.Token
+}
+
+func (al ArrayLiteral) TokenLiteral() string {
+	return al.Token.Literal
+}
+
+func (al ArrayLiteral) Pos() token.Position {
+	return al.Token.Pos
+}
+
+func (al ArrayLiteral) End() token.Position {
+	if al.Rbracket.End.IsValid() {
+		return al.Rbracket.End
+	}
+	return al.Token.End
+}
+
+func (al ArrayLiteral) ExpressionNode() {}
+
+func (al ArrayLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range al.Elements {
+		elements = append(elements, e.String())
+	}
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+	return out.String()
+}
+
+//<expression>[<expression>]
+type IndexExpression struct {
+	Token    token.Token
+	Left     Expression
+	Index    Expression
+	Rbracket token.Token
+}
+
+func (ie IndexExpression) TokenLiteral() string {
+	return ie.Token.Literal
+}
+
+func (ie IndexExpression) Pos() token.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+	return ie.Token.Pos
+}
+
+func (ie IndexExpression) End() token.Position {
+	if ie.Rbracket.End.IsValid() {
+		return ie.Rbracket.End
+	}
+	return ie.Token.End
+}
+
+func (ie IndexExpression) ExpressionNode() {}
+
+func (ie IndexExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ie.Left.String())
+	out.WriteString("[")
+	out.WriteString(ie.Index.String())
+	out.WriteString("])")
+	return out.String()
+}
+
+//{<expression>: <expression>, ...}
+//書いた順番を保つためにmapではなくsliceで持つ
+type HashLiteral struct {
+	Token  token.Token
+	Pairs  []HashPair
+	Rbrace token.Token
+}
+
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+func (hl HashLiteral) TokenLiteral() string {
+	return hl.Token.Literal
+}
+
+func (hl HashLiteral) Pos() token.Position {
+	return hl.Token.Pos
+}
+
+func (hl HashLiteral) End() token.Position {
+	if hl.Rbrace.End.IsValid() {
+		return hl.Rbrace.End
+	}
+	return hl.Token.End
+}
+
+func (hl HashLiteral) ExpressionNode() {}
+
+func (hl HashLiteral) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+":"+pair.Value.String())
+	}
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+//macro<parameters> <blockStatement>
+type MacroLiteral struct {
+	Token      token.Token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (ml MacroLiteral) TokenLiteral() string {
+	return ml.Token.Literal
+}
+
+func (ml MacroLiteral) Pos() token.Position {
+	return ml.Token.Pos
+}
+
+func (ml MacroLiteral) End() token.Position {
+	if ml.Body != nil {
+		return ml.Body.End()
+	}
+	return ml.Token.End
+}
+
+func (ml MacroLiteral) ExpressionNode() {}
+
+func (ml MacroLiteral) String() string {
+	var out bytes.Buffer
+
+	parameter := []string{}
+
+	for _, p := range ml.Parameters {
+		parameter = append(parameter, p.String())
+	}
+	out.WriteString(ml.Token.Literal)
+	out.WriteString("(")
+	out.WriteString(strings.Join(parameter, ","))
+	out.WriteString(")")
+	out.WriteString(ml.Body.String())
+
+	return out.String()
+}
+
+//while (<condition>) <blockStatement>
+type WhileStatement struct {
+	Token     token.Token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws WhileStatement) TokenLiteral() string {
+	return ws.Token.Literal
+}
+
+func (ws WhileStatement) Pos() token.Position {
+	return ws.Token.Pos
+}
+
+func (ws WhileStatement) End() token.Position {
+	if ws.Body != nil {
+		return ws.Body.End()
+	}
+	return ws.Token.End
+}
+
+func (ws WhileStatement) StatementNode() {}
+
+func (ws WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ws.Body.String())
+
+	return out.String()
+}
+
+//for (<variable> in <iterable>) <blockStatement>
+type ForStatement struct {
+	Token    token.Token
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs ForStatement) TokenLiteral() string {
+	return fs.Token.Literal
+}
+
+func (fs ForStatement) Pos() token.Position {
+	return fs.Token.Pos
+}
+
+func (fs ForStatement) End() token.Position {
+	if fs.Body != nil {
+		return fs.Body.End()
+	}
+	return fs.Token.End
+}
+
+func (fs ForStatement) StatementNode() {}
+
+func (fs ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for(")
+	out.WriteString(fs.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+type BreakStatement struct {
+	Token token.Token
+}
+
+func (bs BreakStatement) TokenLiteral() string {
+	return bs.Token.Literal
+}
+
+func (bs BreakStatement) Pos() token.Position {
+	return bs.Token.Pos
+}
+
+func (bs BreakStatement) End() token.Position {
+	return bs.Token.End
+}
+
+func (bs BreakStatement) StatementNode() {}
+
+func (bs BreakStatement) String() string {
+	return bs.Token.Literal + ";"
+}
+
+type ContinueStatement struct {
+	Token token.Token
+}
+
+func (cs ContinueStatement) TokenLiteral() string {
+	return cs.Token.Literal
+}
+
+func (cs ContinueStatement) Pos() token.Position {
+	return cs.Token.Pos
+}
+
+func (cs ContinueStatement) End() token.Position {
+	return cs.Token.End
+}
+
+func (cs ContinueStatement) StatementNode() {}
+
+func (cs ContinueStatement) String() string {
+	return cs.Token.Literal + ";"
+}
+
+//<target> = <value>。targetは識別子か添字式。Operatorは"="か"+="などの複合代入
+type AssignExpression struct {
+	Token    token.Token
+	Target   Expression
+	Operator string
+	Value    Expression
+}
+
+func (ae AssignExpression) TokenLiteral() string {
+	return ae.Token.Literal
+}
+
+func (ae AssignExpression) Pos() token.Position {
+	if ae.Target != nil {
+		return ae.Target.Pos()
+	}
+	return ae.Token.Pos
+}
+
+func (ae AssignExpression) End() token.Position {
+	if ae.Value != nil {
+		return ae.Value.End()
+	}
+	return ae.Token.End
+}
+
+func (ae AssignExpression) ExpressionNode() {}
+
+func (ae AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + " ")
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+
+	return out.String()
+}
@@ -0,0 +1,185 @@
+package ast
+
+type ModifierFunc func(Node) Node
+
+//子ノードを先に書き換えてから、node自身をmodifierに渡す。
+//書き換えたノードはコピーして返すので、元の木は変更しない
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+	case *Program:
+		n := *node
+		n.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&n)
+
+	case *ExpressionStatement:
+		n := *node
+		n.Expression = modifyExpression(node.Expression, modifier)
+		return modifier(&n)
+
+	case *InfixExpression:
+		n := *node
+		n.Left = modifyExpression(node.Left, modifier)
+		n.Right = modifyExpression(node.Right, modifier)
+		return modifier(&n)
+
+	case *AssignExpression:
+		n := *node
+		n.Target = modifyExpression(node.Target, modifier)
+		n.Value = modifyExpression(node.Value, modifier)
+		return modifier(&n)
+
+	case *PrefixExpression:
+		n := *node
+		n.Right = modifyExpression(node.Right, modifier)
+		return modifier(&n)
+
+	case *IndexExpression:
+		n := *node
+		n.Left = modifyExpression(node.Left, modifier)
+		n.Index = modifyExpression(node.Index, modifier)
+		return modifier(&n)
+
+	case *IfExpression:
+		n := *node
+		n.Condition = modifyExpression(node.Condition, modifier)
+		n.Consequence = modifyBlock(node.Consequence, modifier)
+		n.Alternative = modifyBlock(node.Alternative, modifier)
+		return modifier(&n)
+
+	case *BlockStatement:
+		n := *node
+		n.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&n)
+
+	case *ReturnStatementNode:
+		n := *node
+		n.ReturnValue = modifyExpression(node.ReturnValue, modifier)
+		return modifier(&n)
+
+	case *WhileStatement:
+		n := *node
+		n.Condition = modifyExpression(node.Condition, modifier)
+		n.Body = modifyBlock(node.Body, modifier)
+		return modifier(&n)
+
+	case *ForStatement:
+		n := *node
+		n.Variable = modifyIdentifier(node.Variable, modifier)
+		n.Iterable = modifyExpression(node.Iterable, modifier)
+		n.Body = modifyBlock(node.Body, modifier)
+		return modifier(&n)
+
+	case *LetStatementNode:
+		n := *node
+		n.Name = modifyIdentifier(node.Name, modifier)
+		n.Value = modifyExpression(node.Value, modifier)
+		return modifier(&n)
+
+	case *FunctionLiteral:
+		n := *node
+		n.Parameters = modifyIdentifiers(node.Parameters, modifier)
+		n.Body = modifyBlock(node.Body, modifier)
+		return modifier(&n)
+
+	case *MacroLiteral:
+		n := *node
+		n.Parameters = modifyIdentifiers(node.Parameters, modifier)
+		n.Body = modifyBlock(node.Body, modifier)
+		return modifier(&n)
+
+	case *CallExpression:
+		n := *node
+		n.Function = modifyExpression(node.Function, modifier)
+		n.Arguments = modifyExpressions(node.Arguments, modifier)
+		return modifier(&n)
+
+	case *ArrayLiteral:
+		n := *node
+		n.Elements = modifyExpressions(node.Elements, modifier)
+		return modifier(&n)
+
+	case *HashLiteral:
+		n := *node
+		n.Pairs = make([]HashPair, len(node.Pairs))
+		for i, pair := range node.Pairs {
+			n.Pairs[i] = HashPair{
+				Key:   modifyExpression(pair.Key, modifier),
+				Value: modifyExpression(pair.Value, modifier),
+			}
+		}
+		return modifier(&n)
+	}
+
+	//識別子やリテラルなど、子を持たないノード
+	return modifier(node)
+}
+
+//modifierが別の種類のノードを返した場合は、元のノードのままにする
+func modifyExpression(exp Expression, modifier ModifierFunc) Expression {
+	if exp == nil {
+		return nil
+	}
+	if modified, ok := Modify(exp, modifier).(Expression); ok {
+		return modified
+	}
+	return exp
+}
+
+func modifyExpressions(exps []Expression, modifier ModifierFunc) []Expression {
+	if exps == nil {
+		return nil
+	}
+	result := make([]Expression, len(exps))
+	for i, e := range exps {
+		result[i] = modifyExpression(e, modifier)
+	}
+	return result
+}
+
+func modifyStatements(statements []Statement, modifier ModifierFunc) []Statement {
+	if statements == nil {
+		return nil
+	}
+	result := make([]Statement, len(statements))
+	for i, s := range statements {
+		result[i] = s
+		if s == nil {
+			continue
+		}
+		if modified, ok := Modify(s, modifier).(Statement); ok {
+			result[i] = modified
+		}
+	}
+	return result
+}
+
+func modifyBlock(block *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	if modified, ok := Modify(block, modifier).(*BlockStatement); ok {
+		return modified
+	}
+	return block
+}
+
+func modifyIdentifier(ident *Identifier, modifier ModifierFunc) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	if modified, ok := Modify(ident, modifier).(*Identifier); ok {
+		return modified
+	}
+	return ident
+}
+
+func modifyIdentifiers(idents []*Identifier, modifier ModifierFunc) []*Identifier {
+	if idents == nil {
+		return nil
+	}
+	result := make([]*Identifier, len(idents))
+	for i, ident := range idents {
+		result[i] = modifyIdentifier(ident, modifier)
+	}
+	return result
+}
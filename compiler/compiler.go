@@ -0,0 +1,825 @@
+package compiler
+
+import (
+	"fmt"
+	"interpreter-go/ast"
+	"interpreter-go/code"
+	"interpreter-go/object"
+	"interpreter-go/token"
+	"strings"
+)
+
+type Bytecode struct {
+	Instructions code.Instructions
+	Constants    []object.Object
+	GlobalNames  []string //グローバル変数の名前。インデックスの順
+}
+
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
+}
+
+//関数リテラルごとのコンパイル中の命令列
+type CompilationScope struct {
+	instructions        code.Instructions
+	lastInstruction     EmittedInstruction
+	previousInstruction EmittedInstruction
+	loops               []*loopContext //コンパイル中のループ。内側のループが最後
+	localAccesses       []int          //OpGetLocalとOpSetLocalの位置。捕まえられた変数はOpGetCellとOpSetCellに書き換える
+}
+
+//breakとcontinueのジャンプ先
+type loopContext struct {
+	continuePos  int
+	breakJumps   []int  //ループの終わりが決まってから書き換えるOpJumpの位置
+	stackPointer Symbol //ループに入ったときのスタックの深さを入れておく名前のない変数
+}
+
+type Compiler struct {
+	constants   []object.Object
+	symbolTable *SymbolTable
+
+	scopes     []CompilationScope
+	scopeIndex int
+}
+
+func New() *Compiler {
+	symbolTable := NewSymbolTable()
+	for i, name := range object.Builtins.Names() {
+		symbolTable.DefineBuiltin(i, name)
+	}
+
+	return NewWithState(symbolTable, []object.Object{})
+}
+
+//REPLのように、前回のコンパイルで定義したグローバル変数と定数を引き継ぐ
+func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
+	mainScope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+
+	return &Compiler{
+		constants:   constants,
+		symbolTable: s,
+		scopes:      []CompilationScope{mainScope},
+		scopeIndex:  0,
+	}
+}
+
+func (c *Compiler) Compile(node ast.Node) error {
+	switch node := node.(type) {
+	case *ast.Program:
+		c.declare(node.Statements)
+		for _, s := range node.Statements {
+			if err := c.Compile(s); err != nil {
+				return err
+			}
+		}
+		if len(c.constants) > MaxConstants {
+			return fmt.Errorf("too many constants")
+		}
+		if c.symbolTable.numDefinitions > MaxGlobals {
+			return fmt.Errorf("too many global variables")
+		}
+		if len(c.currentInstructions()) > MaxInstructions {
+			return fmt.Errorf("too many instructions")
+		}
+
+	case *ast.ExpressionStatement:
+		if err := c.Compile(node.Expression); err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			if err := c.Compile(s); err != nil {
+				return err
+			}
+		}
+
+	case *ast.LetStatementNode:
+		//値を先にコンパイルする。let x = x + 1の右辺のxは、前に定義したxを参照する
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		symbol := c.symbolTable.Define(node.Name.Value)
+		c.storeSymbol(symbol)
+
+	case *ast.WhileStatement:
+		return c.compileWhileStatement(node)
+
+	case *ast.ForStatement:
+		return c.compileForStatement(node)
+
+	case *ast.BreakStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("break outside loop")
+		}
+		c.restoreStack(loop)
+		loop.breakJumps = append(loop.breakJumps, c.emit(code.OpJump, 9999))
+
+	case *ast.ContinueStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("continue outside loop")
+		}
+		c.restoreStack(loop)
+		c.emit(code.OpJump, loop.continuePos)
+
+	case *ast.ReturnStatementNode:
+		if err := c.Compile(node.ReturnValue); err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(node.Value)
+		if !ok {
+			//どこにも定義がない名前は、評価器と同じく実行したときにエラーにする。
+			//値のないグローバル変数にしておけば、読んだときにVMが報告する
+			symbol = c.symbolTable.Global().Define(node.Value)
+		}
+		if symbol.Scope == BuiltinScope && symbol.Index >= MaxBuiltins {
+			return fmt.Errorf("too many builtins")
+		}
+		c.loadSymbol(symbol)
+
+	case *ast.IntegerLiteral:
+		var integer object.Object = &object.Integer{Value: node.Value}
+		if node.Big != nil {
+			integer = &object.BigInt{Value: node.Big}
+		}
+		c.emit(code.OpConstant, c.addConstant(integer))
+
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(float))
+
+	case *ast.StringLiteral:
+		str := &object.String{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(str))
+
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+
+	case *ast.PrefixExpression:
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+
+		switch node.Token.Type {
+		case token.BANG:
+			c.emit(code.OpBang)
+		case token.MINUS:
+			c.emit(code.OpMinus)
+		case token.BIT_NOT:
+			c.emit(code.OpBitNot)
+		default:
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+
+	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return c.compileLogicalExpression(node)
+		}
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+
+		op, ok := infixOpcodes[node.Operator]
+		if !ok {
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+		c.emit(op)
+
+	case *ast.IfExpression:
+		return c.compileIfExpression(node)
+
+	case *ast.ArrayLiteral:
+		if len(node.Elements) > MaxElements {
+			return fmt.Errorf("too many array elements")
+		}
+		for _, el := range node.Elements {
+			if err := c.Compile(el); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpArray, len(node.Elements))
+
+	case *ast.HashLiteral:
+		if len(node.Pairs)*2 > MaxElements {
+			return fmt.Errorf("too many hash pairs")
+		}
+		for _, pair := range node.Pairs {
+			if err := c.Compile(pair.Key); err != nil {
+				return err
+			}
+			if err := c.Compile(pair.Value); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpHash, len(node.Pairs)*2)
+
+	case *ast.AssignExpression:
+		return c.compileAssignExpression(node)
+
+	case *ast.IndexExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Index); err != nil {
+			return err
+		}
+		c.emit(code.OpIndex)
+
+	case *ast.FunctionLiteral:
+		return c.compileFunctionLiteral(node)
+
+	case *ast.CallExpression:
+		if len(node.Arguments) > MaxArguments {
+			return fmt.Errorf("too many arguments")
+		}
+		if err := c.Compile(node.Function); err != nil {
+			return err
+		}
+		for _, a := range node.Arguments {
+			if err := c.Compile(a); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpCall, len(node.Arguments))
+
+	default:
+		return fmt.Errorf("unsupported node %T", node)
+	}
+
+	return nil
+}
+
+var infixOpcodes = map[string]code.Opcode{
+	"+":  code.OpAdd,
+	"-":  code.OpSub,
+	"*":  code.OpMul,
+	"/":  code.OpDiv,
+	"%":  code.OpMod,
+	"&":  code.OpBitAnd,
+	"|":  code.OpBitOr,
+	"^":  code.OpBitXor,
+	"<<": code.OpShiftLeft,
+	">>": code.OpShiftRight,
+	">":  code.OpGreaterThan,
+	"<":  code.OpLessThan,
+	">=": code.OpGreaterEqual,
+	"<=": code.OpLessEqual,
+	"==": code.OpEqual,
+	"!=": code.OpNotEqual,
+}
+
+//&&は偽、||は真のオペランドが出た時点で結果が決まるので、残りを飛ばしてその真偽値を積む。
+//||はオペランドを反転してから判定すれば、&&と同じ形のジャンプで書ける
+func (c *Compiler) compileLogicalExpression(node *ast.InfixExpression) error {
+	decided, otherwise := code.OpFalse, code.OpTrue
+	if node.Operator == "||" {
+		decided, otherwise = code.OpTrue, code.OpFalse
+	}
+
+	jumps := []int{}
+	for _, operand := range []ast.Expression{node.Left, node.Right} {
+		if err := c.Compile(operand); err != nil {
+			return err
+		}
+		if node.Operator == "||" {
+			c.emit(code.OpBang)
+		}
+		jumps = append(jumps, c.emit(code.OpJumpNotTruthy, 9999))
+	}
+
+	c.emit(otherwise)
+	jumpPos := c.emit(code.OpJump, 9999)
+
+	decidedPos := len(c.currentInstructions())
+	for _, pos := range jumps {
+		c.changeOperand(pos, decidedPos)
+	}
+	c.emit(decided)
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+	return nil
+}
+
+//ifは式なので、どちらの分岐も必ず値を1つ積むようにする
+func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
+	if err := c.Compile(node.Condition); err != nil {
+		return err
+	}
+
+	//ジャンプ先は後で書き換える
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	if err := c.compileBranch(node.Consequence); err != nil {
+		return err
+	}
+
+	jumpPos := c.emit(code.OpJump, 9999)
+
+	afterConsequencePos := len(c.currentInstructions())
+	c.changeOperand(jumpNotTruthyPos, afterConsequencePos)
+
+	if node.Alternative == nil {
+		c.emit(code.OpNull)
+	} else {
+		if err := c.compileBranch(node.Alternative); err != nil {
+			return err
+		}
+	}
+
+	afterAlternativePos := len(c.currentInstructions())
+	c.changeOperand(jumpPos, afterAlternativePos)
+
+	return nil
+}
+
+func (c *Compiler) compileBranch(block *ast.BlockStatement) error {
+	if err := c.Compile(block); err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else if !c.lastInstructionIs(code.OpReturnValue) {
+		//空のブロックやletで終わるブロックは値を持たない
+		c.emit(code.OpNull)
+	}
+	return nil
+}
+
+//代入式は、代入した値をスタックに残す
+func (c *Compiler) compileAssignExpression(node *ast.AssignExpression) error {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(target.Value)
+		if !ok || symbol.Scope == BuiltinScope {
+			return fmt.Errorf("assignment to undeclared identifier: %s", target.Value)
+		}
+
+		if node.Operator != "=" {
+			c.loadSymbol(symbol)
+		}
+		if err := c.compileAssignedValue(node); err != nil {
+			return err
+		}
+		c.storeSymbol(symbol)
+		c.loadSymbol(symbol)
+
+	case *ast.IndexExpression:
+		if err := c.Compile(target.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(target.Index); err != nil {
+			return err
+		}
+		if node.Operator != "=" {
+			c.emit(code.OpDup2)
+			c.emit(code.OpIndex)
+		}
+		if err := c.compileAssignedValue(node); err != nil {
+			return err
+		}
+		c.emit(code.OpSetIndex)
+
+	default:
+		return fmt.Errorf("invalid assignment target: %s", node.Target.String())
+	}
+	return nil
+}
+
+//複合代入なら、スタックに積んである今の値と右辺を演算する
+func (c *Compiler) compileAssignedValue(node *ast.AssignExpression) error {
+	if err := c.Compile(node.Value); err != nil {
+		return err
+	}
+	if node.Operator == "=" {
+		return nil
+	}
+
+	operator := strings.TrimSuffix(node.Operator, "=")
+	op, ok := infixOpcodes[operator]
+	if !ok {
+		return fmt.Errorf("unknown operator %s", operator)
+	}
+	c.emit(op)
+	return nil
+}
+
+//ループは文なので、スタックに値を残さない
+func (c *Compiler) compileWhileStatement(node *ast.WhileStatement) error {
+	stackPointer := c.saveStack()
+	loopStart := len(c.currentInstructions())
+
+	if err := c.Compile(node.Condition); err != nil {
+		return err
+	}
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	if err := c.compileLoopBody(node.Body, loopStart, stackPointer); err != nil {
+		return err
+	}
+
+	c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+	c.clearResult()
+	return nil
+}
+
+//イテレーターは名前のない変数に入れておく
+func (c *Compiler) compileForStatement(node *ast.ForStatement) error {
+	if err := c.Compile(node.Iterable); err != nil {
+		return err
+	}
+	c.emit(code.OpIter)
+	iterator := c.symbolTable.DefineHidden()
+	c.storeSymbol(iterator)
+
+	stackPointer := c.saveStack()
+	loopStart := len(c.currentInstructions())
+	c.loadSymbol(iterator)
+	iterNextPos := c.emit(code.OpIterNext, 9999)
+
+	variable := c.symbolTable.Define(node.Variable.Value)
+	c.storeSymbol(variable)
+
+	if err := c.compileLoopBody(node.Body, loopStart, stackPointer); err != nil {
+		return err
+	}
+
+	c.changeOperand(iterNextPos, len(c.currentInstructions()))
+	c.clearResult()
+	return nil
+}
+
+//本体の後にloopStartへ戻るジャンプを置き、breakのジャンプ先をループの直後にする
+func (c *Compiler) compileLoopBody(body *ast.BlockStatement, loopStart int, stackPointer Symbol) error {
+	loop := &loopContext{continuePos: loopStart, stackPointer: stackPointer}
+	scope := &c.scopes[c.scopeIndex]
+	scope.loops = append(scope.loops, loop)
+
+	if err := c.Compile(body); err != nil {
+		return err
+	}
+	c.emit(code.OpJump, loopStart)
+
+	scope = &c.scopes[c.scopeIndex]
+	scope.loops = scope.loops[:len(scope.loops)-1]
+
+	afterLoopPos := len(c.currentInstructions())
+	for _, pos := range loop.breakJumps {
+		c.changeOperand(pos, afterLoopPos)
+	}
+	return nil
+}
+
+//本体の式文や条件の値が、プログラムの結果として残らないようにNULLをpopしておく
+func (c *Compiler) clearResult() {
+	c.emit(code.OpNull)
+	c.emit(code.OpPop)
+}
+
+//ループに入るときのスタックの深さを、名前のない変数に記録する
+func (c *Compiler) saveStack() Symbol {
+	c.emit(code.OpStackPointer)
+	symbol := c.symbolTable.DefineHidden()
+	c.storeSymbol(symbol)
+	return symbol
+}
+
+//[1, if (x) { break; }]のように式の途中でループを抜けると、評価しかけの値がスタックに残るので、
+//ループに入ったときの深さまで戻してからジャンプする
+func (c *Compiler) restoreStack(loop *loopContext) {
+	c.loadSymbol(loop.stackPointer)
+	c.emit(code.OpRestoreStack)
+}
+
+func (c *Compiler) currentLoop() *loopContext {
+	loops := c.scopes[c.scopeIndex].loops
+	if len(loops) == 0 {
+		return nil
+	}
+	return loops[len(loops)-1]
+}
+
+//オペランドの幅で決まる上限。code.Makeは収まらない上位のバイトを黙って捨てるので、
+//超えるプログラムはコンパイルのときにエラーにする
+const (
+	//OpGetLocalとOpSetLocalのオペランドは1バイトなので、1つの関数のローカル変数はこの数まで。
+	//ループが使う名前のない変数も数える
+	MaxLocals = 256
+	//OpCallの引数の数とOpClosureの自由変数の数は1バイト
+	MaxArguments     = 255
+	MaxFreeVariables = 255
+	MaxBuiltins      = 256
+	//OpGetGlobalとOpConstantのインデックス、OpArrayとOpHashの要素数、ジャンプ先は2バイト
+	MaxGlobals      = 65536
+	MaxConstants    = 65536
+	MaxElements     = 65535
+	MaxInstructions = 65535 //ジャンプ先は命令列の末尾を指すこともあるので、命令列の長さまで収まる必要がある
+)
+
+func (c *Compiler) compileFunctionLiteral(node *ast.FunctionLiteral) error {
+	c.enterScope()
+
+	//関数の中の自分の名前も、評価器と同じく関数を入れた外側の変数を読む。
+	//その変数に別の値を代入すれば、再帰呼び出しもその値を呼ぶ
+	for _, p := range node.Parameters {
+		c.symbolTable.Define(p.Value)
+	}
+	c.declare(node.Body.Statements)
+
+	if err := c.Compile(node.Body); err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.replaceLastPopWithReturn()
+	}
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+
+	freeSymbols := c.symbolTable.FreeSymbols
+	numLocals := c.symbolTable.numDefinitions
+	if numLocals > MaxLocals {
+		return fmt.Errorf("too many local variables")
+	}
+	if len(freeSymbols) > MaxFreeVariables {
+		return fmt.Errorf("too many free variables")
+	}
+	if len(c.currentInstructions()) > MaxInstructions {
+		return fmt.Errorf("too many instructions")
+	}
+	cells := c.symbolTable.Cells()
+	localNames := c.symbolTable.Names()
+	c.useCells(cells)
+	instructions := c.leaveScope()
+
+	freeNames := []string{}
+	for _, s := range freeSymbols {
+		c.loadCell(s)
+		freeNames = append(freeNames, s.Name)
+	}
+
+	compiledFn := &object.CompiledFunction{
+		Instructions:  instructions,
+		NumLocals:     numLocals,
+		NumParameters: len(node.Parameters),
+		Cells:         cells,
+		LocalNames:    localNames,
+		FreeNames:     freeNames,
+	}
+
+	c.emit(code.OpClosure, c.addConstant(compiledFn), len(freeSymbols))
+	return nil
+}
+
+//評価器では関数の中の名前を呼び出したときに探すので、後のletで定義する関数も呼べる。
+//同じように見えるように、このスコープのletとforで定義する名前の場所を先に決めておく
+func (c *Compiler) declare(statements []ast.Statement) {
+	for _, s := range statements {
+		c.declareIn(s)
+	}
+}
+
+//関数リテラルの中は、その関数をコンパイルするときに決める
+func (c *Compiler) declareIn(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.LetStatementNode:
+		c.symbolTable.Declare(node.Name.Value)
+		c.declareIn(node.Value)
+	case *ast.ForStatement:
+		c.symbolTable.Declare(node.Variable.Value)
+		c.declareIn(node.Iterable)
+		c.declare(node.Body.Statements)
+	case *ast.WhileStatement:
+		c.declareIn(node.Condition)
+		c.declare(node.Body.Statements)
+	case *ast.BlockStatement:
+		c.declare(node.Statements)
+	case *ast.ExpressionStatement:
+		c.declareIn(node.Expression)
+	case *ast.ReturnStatementNode:
+		c.declareIn(node.ReturnValue)
+	case *ast.IfExpression:
+		c.declareIn(node.Condition)
+		c.declare(node.Consequence.Statements)
+		if node.Alternative != nil {
+			c.declare(node.Alternative.Statements)
+		}
+	case *ast.PrefixExpression:
+		c.declareIn(node.Right)
+	case *ast.InfixExpression:
+		c.declareIn(node.Left)
+		c.declareIn(node.Right)
+	case *ast.AssignExpression:
+		c.declareIn(node.Target)
+		c.declareIn(node.Value)
+	case *ast.IndexExpression:
+		c.declareIn(node.Left)
+		c.declareIn(node.Index)
+	case *ast.CallExpression:
+		c.declareIn(node.Function)
+		for _, a := range node.Arguments {
+			c.declareIn(a)
+		}
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			c.declareIn(el)
+		}
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			c.declareIn(pair.Key)
+			c.declareIn(pair.Value)
+		}
+	}
+}
+
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.addLocalAccess(c.emit(code.OpGetLocal, s.Index))
+	case BuiltinScope:
+		c.emit(code.OpGetBuiltin, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	}
+}
+
+func (c *Compiler) storeSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpSetGlobal, s.Index)
+	case LocalScope:
+		c.addLocalAccess(c.emit(code.OpSetLocal, s.Index))
+	case FreeScope:
+		c.emit(code.OpSetFree, s.Index)
+	}
+}
+
+//クロージャに渡すセルを積む
+func (c *Compiler) loadCell(s Symbol) {
+	switch s.Scope {
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpFreeCell, s.Index)
+	}
+}
+
+func (c *Compiler) addLocalAccess(pos int) {
+	scope := &c.scopes[c.scopeIndex]
+	scope.localAccesses = append(scope.localAccesses, pos)
+}
+
+//変数が捕まえられたかは関数の最後までコンパイルしないとわからないので、
+//それまでに書いたOpGetLocalとOpSetLocalを、セルを読み書きする命令に書き換える
+func (c *Compiler) useCells(cells []int) {
+	if len(cells) == 0 {
+		return
+	}
+	isCell := map[int]bool{}
+	for _, i := range cells {
+		isCell[i] = true
+	}
+
+	ins := c.currentInstructions()
+	for _, pos := range c.scopes[c.scopeIndex].localAccesses {
+		if !isCell[int(code.ReadUint8(ins[pos+1:]))] {
+			continue
+		}
+		switch code.Opcode(ins[pos]) {
+		case code.OpGetLocal:
+			ins[pos] = byte(code.OpGetCell)
+		case code.OpSetLocal:
+			ins[pos] = byte(code.OpSetCell)
+		}
+	}
+}
+
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Constants:    c.constants,
+		GlobalNames:  c.symbolTable.Names(),
+	}
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+//命令を追加して、その位置を返す
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	ins := code.Make(op, operands...)
+	pos := c.addInstruction(ins)
+
+	c.setLastInstruction(op, pos)
+
+	return pos
+}
+
+func (c *Compiler) addInstruction(ins []byte) int {
+	posNewInstruction := len(c.currentInstructions())
+	updatedInstructions := append(c.currentInstructions(), ins...)
+
+	c.scopes[c.scopeIndex].instructions = updatedInstructions
+
+	return posNewInstruction
+}
+
+func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
+	previous := c.scopes[c.scopeIndex].lastInstruction
+	last := EmittedInstruction{Opcode: op, Position: pos}
+
+	c.scopes[c.scopeIndex].previousInstruction = previous
+	c.scopes[c.scopeIndex].lastInstruction = last
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+func (c *Compiler) removeLastPop() {
+	last := c.scopes[c.scopeIndex].lastInstruction
+	previous := c.scopes[c.scopeIndex].previousInstruction
+
+	old := c.currentInstructions()
+	new := old[:last.Position]
+
+	c.scopes[c.scopeIndex].instructions = new
+	c.scopes[c.scopeIndex].lastInstruction = previous
+}
+
+func (c *Compiler) replaceLastPopWithReturn() {
+	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
+	c.replaceInstruction(lastPos, code.Make(code.OpReturnValue))
+
+	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+}
+
+func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+	ins := c.currentInstructions()
+
+	for i := 0; i < len(newInstruction); i++ {
+		ins[pos+i] = newInstruction[i]
+	}
+}
+
+func (c *Compiler) changeOperand(opPos int, operand int) {
+	op := code.Opcode(c.currentInstructions()[opPos])
+	newInstruction := code.Make(op, operand)
+
+	c.replaceInstruction(opPos, newInstruction)
+}
+
+func (c *Compiler) enterScope() {
+	scope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+	c.scopes = append(c.scopes, scope)
+	c.scopeIndex++
+
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+
+	c.symbolTable = c.symbolTable.Outer
+
+	return instructions
+}
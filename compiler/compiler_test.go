@@ -0,0 +1,652 @@
+package compiler
+
+import (
+	"fmt"
+	"interpreter-go/ast"
+	"interpreter-go/code"
+	"interpreter-go/lexer"
+	"interpreter-go/object"
+	"interpreter-go/parser"
+	"strings"
+	"testing"
+)
+
+type compilerTestCase struct {
+	input                string
+	expectedConstants    []interface{}
+	expectedInstructions []code.Instructions
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "1 + 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "1 < 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpLessThan),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "-1; !true",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpMinus),
+				code.Make(code.OpPop),
+				code.Make(code.OpTrue),
+				code.Make(code.OpBang),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "if (true) { 10 }; 3333;",
+			expectedConstants: []interface{}{10, 3333},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 10),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpJump, 11),
+				// 0010
+				code.Make(code.OpNull),
+				// 0011
+				code.Make(code.OpPop),
+				// 0012
+				code.Make(code.OpConstant, 1),
+				// 0015
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "if (true) { let a = 1; } else { 20 }",
+			expectedConstants: []interface{}{1, 20},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 14),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpSetGlobal, 0),
+				// 0010
+				code.Make(code.OpNull),
+				// 0011
+				code.Make(code.OpJump, 17),
+				// 0014
+				code.Make(code.OpConstant, 1),
+				// 0017
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "true && false",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 12),
+				// 0004
+				code.Make(code.OpFalse),
+				// 0005
+				code.Make(code.OpJumpNotTruthy, 12),
+				// 0008
+				code.Make(code.OpTrue),
+				// 0009
+				code.Make(code.OpJump, 13),
+				// 0012
+				code.Make(code.OpFalse),
+				// 0013
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "true || false",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpBang),
+				// 0002
+				code.Make(code.OpJumpNotTruthy, 14),
+				// 0005
+				code.Make(code.OpFalse),
+				// 0006
+				code.Make(code.OpBang),
+				// 0007
+				code.Make(code.OpJumpNotTruthy, 14),
+				// 0010
+				code.Make(code.OpFalse),
+				// 0011
+				code.Make(code.OpJump, 15),
+				// 0014
+				code.Make(code.OpTrue),
+				// 0015
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "let one = 1; let two = one; two;",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestLoops(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "while (true) { break; }",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpStackPointer),
+				// 0001
+				code.Make(code.OpSetGlobal, 0),
+				// 0004
+				code.Make(code.OpTrue),
+				// 0005
+				code.Make(code.OpJumpNotTruthy, 18),
+				// 0008
+				code.Make(code.OpGetGlobal, 0),
+				// 0011
+				code.Make(code.OpRestoreStack),
+				// 0012
+				code.Make(code.OpJump, 18),
+				// 0015
+				code.Make(code.OpJump, 4),
+				// 0018
+				code.Make(code.OpNull),
+				// 0019
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "for (x in [1]) { continue; }",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpArray, 1),
+				// 0006
+				code.Make(code.OpIter),
+				// 0007
+				code.Make(code.OpSetGlobal, 1),
+				// 0010
+				code.Make(code.OpStackPointer),
+				// 0011
+				code.Make(code.OpSetGlobal, 2),
+				// 0014
+				code.Make(code.OpGetGlobal, 1),
+				// 0017
+				code.Make(code.OpIterNext, 33),
+				// 0020
+				code.Make(code.OpSetGlobal, 0),
+				// 0023
+				code.Make(code.OpGetGlobal, 2),
+				// 0026
+				code.Make(code.OpRestoreStack),
+				// 0027
+				code.Make(code.OpJump, 14),
+				// 0030
+				code.Make(code.OpJump, 14),
+				// 0033
+				code.Make(code.OpNull),
+				// 0034
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestAssignments(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "let x = 1; x += 2;",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "let a = [1]; a[0] *= 2;",
+			expectedConstants: []interface{}{1, 0, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpDup2),
+				code.Make(code.OpIndex),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpMul),
+				code.Make(code.OpSetIndex),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestCollections(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `[1, "a"][0]`,
+			expectedConstants: []interface{}{1, "a", 0},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpArray, 2),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpIndex),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `{1: 2}`,
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpHash, 2),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestFunctions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: "fn() { return 5 + 10 }",
+			expectedConstants: []interface{}{
+				5,
+				10,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "fn() { }",
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpReturn),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "let f = fn(a) { len(a) }; f([]);",
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetBuiltin, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpArray, 0),
+				code.Make(code.OpCall, 1),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestClosures(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			//捕まえられたローカル変数はセルを通して読み書きし、クロージャにはセルそのものを渡す
+			input: "fn() { let c = 0; fn() { c = 1 }; c }",
+			expectedConstants: []interface{}{
+				0,
+				1,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpSetFree, 0),
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetCell, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpClosure, 2, 1),
+					code.Make(code.OpPop),
+					code.Make(code.OpGetCell, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 3, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "fn(a) { fn(b) { a + b } }",
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpClosure, 0, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "let countDown = fn(x) { countDown(x - 1); };",
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpGetGlobal, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSub),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpSetGlobal, 0),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+//定義のない名前は値のないグローバル変数になり、実行したときにVMがエラーにする
+func TestUndefinedIdentifiers(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: "let a = fn() { b };",
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetGlobal, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpSetGlobal, 0),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestAssignmentErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 1", "assignment to undeclared identifier: x"},
+	}
+
+	for _, tt := range tests {
+		err := New().Compile(parse(tt.input))
+		if err == nil {
+			t.Errorf("expected compiler error for %q", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. got=%q, want=%q", err, tt.expected)
+		}
+	}
+}
+
+//ローカル変数のインデックスは1バイトに収まらなければならない
+func TestTooManyLocals(t *testing.T) {
+	lets := func(n int) string {
+		var out strings.Builder
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&out, "let x%d = %d; ", i, i)
+		}
+		return out.String()
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(a) { " + lets(255) + "}", ""},
+		{"fn(a) { " + lets(256) + "}", "too many local variables"},
+		{"fn() { " + lets(254) + "for (x in []) { } }", "too many local variables"},
+		{"fn() { fn() { " + lets(300) + "} }", "too many local variables"},
+		{lets(300), ""},
+	}
+
+	for _, tt := range tests {
+		err := New().Compile(parse(tt.input))
+		if tt.expected == "" {
+			if err != nil {
+				t.Errorf("unexpected compiler error: %s", err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("expected compiler error %q", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. got=%q, want=%q", err, tt.expected)
+		}
+	}
+}
+
+func TestOperandLimits(t *testing.T) {
+	repeat := func(format string, n int, sep string) string {
+		items := make([]string, n)
+		for i := range items {
+			items[i] = fmt.Sprintf(format, i)
+		}
+		return strings.Join(items, sep)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"puts(" + repeat("%d", 255, ", ") + ")", ""},
+		{"puts(" + repeat("%d", 256, ", ") + ")", "too many arguments"},
+		{"fn() { " + repeat("let x%d = 0;", 255, " ") + " fn() { " + repeat("x%d", 255, " + ") + " } }", ""},
+		{"fn() { " + repeat("let x%d = 0;", 256, " ") + " fn() { " + repeat("x%d", 256, " + ") + " } }", "too many free variables"},
+		{repeat(`"%d";`, 65537, " "), "too many constants"},
+		{repeat("let x%d = true;", 65537, " "), "too many global variables"},
+		{strings.Repeat("true; ", 32767), ""},
+		{strings.Repeat("true; ", 32768), "too many instructions"},
+		{"fn() { " + strings.Repeat("true; ", 32768) + "}", "too many instructions"},
+		{"[true" + strings.Repeat(", true", 65535) + "]", "too many array elements"},
+		{"{" + repeat("%d: true", 32768, ", ") + "}", "too many hash pairs"},
+	}
+
+	for _, tt := range tests {
+		err := New().Compile(parse(tt.input))
+		if tt.expected == "" {
+			if err != nil {
+				t.Errorf("unexpected compiler error: %s", err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("expected compiler error %q", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. got=%q, want=%q", err, tt.expected)
+		}
+	}
+}
+
+//組み込み関数を増やすとほかのテストに影響するので、シンボルテーブルに直接定義する
+func TestTooManyBuiltins(t *testing.T) {
+	symbolTable := NewSymbolTable()
+	symbolTable.DefineBuiltin(MaxBuiltins-1, "last")
+	symbolTable.DefineBuiltin(MaxBuiltins, "overflow")
+
+	if err := NewWithState(symbolTable, []object.Object{}).Compile(parse("last")); err != nil {
+		t.Errorf("unexpected compiler error: %s", err)
+	}
+	err := NewWithState(symbolTable, []object.Object{}).Compile(parse("overflow"))
+	if err == nil || err.Error() != "too many builtins" {
+		t.Errorf("wrong error. got=%v, want=%q", err, "too many builtins")
+	}
+}
+
+func runCompilerTests(t *testing.T, tests []compilerTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		compiler := New()
+		if err := compiler.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		bytecode := compiler.Bytecode()
+
+		testInstructions(t, tt.input, tt.expectedInstructions, bytecode.Instructions)
+		testConstants(t, tt.input, tt.expectedConstants, bytecode.Constants)
+	}
+}
+
+func parse(input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram()
+}
+
+func testInstructions(t *testing.T, input string, expected []code.Instructions, actual code.Instructions) {
+	t.Helper()
+
+	concatted := code.Instructions{}
+	for _, ins := range expected {
+		concatted = append(concatted, ins...)
+	}
+
+	if actual.String() != concatted.String() {
+		t.Errorf("%q: wrong instructions.\nwant=\n%s\ngot=\n%s", input, concatted, actual)
+	}
+}
+
+func testConstants(t *testing.T, input string, expected []interface{}, actual []object.Object) {
+	t.Helper()
+
+	if len(expected) != len(actual) {
+		t.Errorf("%q: wrong number of constants. got=%d, want=%d", input, len(actual), len(expected))
+		return
+	}
+
+	for i, constant := range expected {
+		switch constant := constant.(type) {
+		case int:
+			integer, ok := actual[i].(*object.Integer)
+			if !ok || integer.Value != int64(constant) {
+				t.Errorf("%q: constant %d wrong. got=%T(%+v), want=%d", input, i, actual[i], actual[i], constant)
+			}
+		case string:
+			str, ok := actual[i].(*object.String)
+			if !ok || str.Value != constant {
+				t.Errorf("%q: constant %d wrong. got=%T(%+v), want=%q", input, i, actual[i], actual[i], constant)
+			}
+		case []code.Instructions:
+			fn, ok := actual[i].(*object.CompiledFunction)
+			if !ok {
+				t.Errorf("%q: constant %d not a function. got=%T", input, i, actual[i])
+				continue
+			}
+			testInstructions(t, input, constant, fn.Instructions)
+		}
+	}
+}
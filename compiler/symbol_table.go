@@ -0,0 +1,179 @@
+package compiler
+
+type SymbolScope string
+
+const (
+	GlobalScope  SymbolScope = "GLOBAL"
+	LocalScope   SymbolScope = "LOCAL"
+	BuiltinScope SymbolScope = "BUILTIN"
+	FreeScope    SymbolScope = "FREE"
+)
+
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+//関数ごとに1つ作り、Outerで外側の関数のテーブルをたどる
+type SymbolTable struct {
+	Outer *SymbolTable
+
+	store          map[string]Symbol
+	numDefinitions int
+	names          []string //インデックスごとの変数の名前。名前のない変数は空文字列
+
+	FreeSymbols []Symbol
+
+	declared map[string]Symbol //場所だけ決めて、まだletをコンパイルしていない変数
+
+	captured map[int]bool //内側の関数から参照されたローカル変数のインデックス
+}
+
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{store: map[string]Symbol{}, declared: map[string]Symbol{}, captured: map[int]bool{}}
+}
+
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+func (s *SymbolTable) Define(name string) Symbol {
+	if symbol, ok := s.declared[name]; ok {
+		delete(s.declared, name)
+		s.store[name] = symbol
+		return symbol
+	}
+
+	scope := LocalScope
+	if s.Outer == nil {
+		scope = GlobalScope
+	}
+
+	//同じスコープで再定義した場合は、同じ場所に上書きする。
+	//ループの中のletが、ループの条件から見えるようにするため
+	if existing, ok := s.store[name]; ok && existing.Scope == scope {
+		return existing
+	}
+
+	symbol := Symbol{Name: name, Scope: scope, Index: s.numDefinitions}
+	s.store[name] = symbol
+	s.numDefinitions++
+	s.names = append(s.names, name)
+	return symbol
+}
+
+//letより前に、変数の場所だけを決めておく。後で定義する関数を呼ぶ関数のように、
+//内側の関数からはletより前でもこの変数が見える
+func (s *SymbolTable) Declare(name string) {
+	scope := LocalScope
+	if s.Outer == nil {
+		scope = GlobalScope
+	}
+	if existing, ok := s.store[name]; ok && existing.Scope == scope {
+		return
+	}
+	if _, ok := s.declared[name]; ok {
+		return
+	}
+
+	s.declared[name] = Symbol{Name: name, Scope: scope, Index: s.numDefinitions}
+	s.numDefinitions++
+	s.names = append(s.names, name)
+}
+
+//一番外側のテーブル
+func (s *SymbolTable) Global() *SymbolTable {
+	for s.Outer != nil {
+		s = s.Outer
+	}
+	return s
+}
+
+//コンパイラが内部で使う、名前で参照できない変数
+func (s *SymbolTable) DefineHidden() Symbol {
+	scope := LocalScope
+	if s.Outer == nil {
+		scope = GlobalScope
+	}
+
+	symbol := Symbol{Scope: scope, Index: s.numDefinitions}
+	s.numDefinitions++
+	s.names = append(s.names, "")
+	return symbol
+}
+
+//定義した変数の名前をインデックスの順に返す。VMが値の入っていない変数を報告するのに使う
+func (s *SymbolTable) Names() []string {
+	return append([]string{}, s.names...)
+}
+
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{Name: original.Name, Index: len(s.FreeSymbols) - 1, Scope: FreeScope}
+	s.store[original.Name] = symbol
+	return symbol
+}
+
+//letより前の参照は、let x = x + 1の右辺のxのように、外側で定義した同じ名前を指す。
+//外側にもなければ、まだ値の入っていないこのスコープの変数になる
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	symbol, ok := s.store[name]
+	if !ok && s.Outer != nil {
+		symbol, ok = s.ResolveOuter(name)
+	}
+	if !ok {
+		symbol, ok = s.declared[name]
+	}
+	return symbol, ok
+}
+
+//内側の関数は後から呼ばれるので、まだletをコンパイルしていない変数もこのスコープのものとして見える
+func (s *SymbolTable) resolveFromInner(name string) (Symbol, bool) {
+	if symbol, ok := s.declared[name]; ok {
+		return symbol, true
+	}
+	symbol, ok := s.store[name]
+	if !ok && s.Outer != nil {
+		return s.ResolveOuter(name)
+	}
+	return symbol, ok
+}
+
+//外側の関数のローカル変数が見つかった場合は、自由変数として登録する。
+//そのローカル変数は、両方の関数から書き換えられるようにセルに入れる
+func (s *SymbolTable) ResolveOuter(name string) (Symbol, bool) {
+	symbol, ok := s.Outer.resolveFromInner(name)
+	if !ok {
+		return symbol, ok
+	}
+
+	if symbol.Scope == GlobalScope || symbol.Scope == BuiltinScope {
+		return symbol, ok
+	}
+
+	if symbol.Scope == LocalScope {
+		s.Outer.captured[symbol.Index] = true
+	}
+	return s.defineFree(symbol), true
+}
+
+//セルに入れるローカル変数のインデックスを小さい順に返す
+func (s *SymbolTable) Cells() []int {
+	cells := []int{}
+	for i := 0; i < s.numDefinitions; i++ {
+		if s.captured[i] {
+			cells = append(cells, i)
+		}
+	}
+	return cells
+}
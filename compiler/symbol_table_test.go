@@ -0,0 +1,97 @@
+package compiler
+
+import "testing"
+
+func TestDefineAndResolve(t *testing.T) {
+	global := NewSymbolTable()
+	a := global.Define("a")
+	b := global.Define("b")
+
+	local := NewEnclosedSymbolTable(global)
+	c := local.Define("c")
+
+	nested := NewEnclosedSymbolTable(local)
+	d := nested.Define("d")
+
+	expected := []struct {
+		table  *SymbolTable
+		name   string
+		symbol Symbol
+	}{
+		{global, "a", Symbol{Name: "a", Scope: GlobalScope, Index: 0}},
+		{global, "b", Symbol{Name: "b", Scope: GlobalScope, Index: 1}},
+		{local, "a", a},
+		{local, "c", Symbol{Name: "c", Scope: LocalScope, Index: 0}},
+		{nested, "b", b},
+		{nested, "c", Symbol{Name: "c", Scope: FreeScope, Index: 0}},
+		{nested, "d", d},
+	}
+
+	for _, tt := range expected {
+		result, ok := tt.table.Resolve(tt.name)
+		if !ok {
+			t.Errorf("name %s not resolvable", tt.name)
+			continue
+		}
+		if result != tt.symbol {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", tt.name, tt.symbol, result)
+		}
+	}
+
+	if c.Scope != LocalScope {
+		t.Errorf("c has wrong scope. got=%s", c.Scope)
+	}
+
+	if len(nested.FreeSymbols) != 1 || nested.FreeSymbols[0] != c {
+		t.Errorf("wrong free symbols. got=%+v", nested.FreeSymbols)
+	}
+}
+
+func TestRedefine(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineBuiltin(0, "len")
+	a := global.Define("a")
+	hidden := global.DefineHidden()
+
+	if redefined := global.Define("a"); redefined != a {
+		t.Errorf("redefined symbol should reuse the index. got=%+v, want=%+v", redefined, a)
+	}
+	if hidden.Index != 1 || hidden.Scope != GlobalScope {
+		t.Errorf("wrong hidden symbol. got=%+v", hidden)
+	}
+
+	//組み込み関数と同じ名前は、グローバル変数として新しく定義する
+	l := global.Define("len")
+	if l.Scope != GlobalScope || l.Index != 2 {
+		t.Errorf("wrong symbol for len. got=%+v", l)
+	}
+}
+
+func TestResolveUnresolvable(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	local := NewEnclosedSymbolTable(global)
+	local.Define("b")
+
+	if _, ok := local.Resolve("c"); ok {
+		t.Errorf("name c resolved, but was expected not to")
+	}
+}
+
+func TestDefineBuiltin(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineBuiltin(3, "len")
+
+	local := NewEnclosedSymbolTable(global)
+
+	if s, _ := local.Resolve("len"); s != (Symbol{Name: "len", Scope: BuiltinScope, Index: 3}) {
+		t.Errorf("len resolved wrong. got=%+v", s)
+	}
+
+	//組み込み関数は引数で隠せる
+	local.Define("len")
+	if s, _ := local.Resolve("len"); s.Scope != LocalScope {
+		t.Errorf("len was not shadowed. got=%+v", s)
+	}
+}
@@ -0,0 +1,566 @@
+//評価器とVMが同じ結果を返すことを確かめるための共通のテストケース
+package conformance
+
+import (
+	"interpreter-go/object"
+	"testing"
+)
+
+//Expectedに指定すると、このメッセージのobject.Errorを期待する
+type Error string
+
+//Expectedに指定すると、この10進表記のobject.BigIntを期待する
+type BigInt string
+
+//Expectedはint(INTEGER)、float64(FLOAT)、bool(BOOLEAN)、string(STRING)、nil(NULL)、
+//[]int(INTEGERの配列)、map[string]int(キーがSTRINGのハッシュ)、BigInt、Error
+type Case struct {
+	Input    string
+	Expected interface{}
+}
+
+type Group struct {
+	Name  string
+	Cases []Case
+}
+
+var Groups = []Group{
+	{
+		Name: "IntegerExpression",
+		Cases: []Case{
+			{"5", 5},
+			{"10", 10},
+			{"-5", -5},
+			{"-10", -10},
+			{"5 + 10", 15},
+			{"10 - 5", 5},
+			{"10 * 5", 50},
+			{"10 / 5", 2},
+			{"10 - 10", 0},
+			{"(10 - (10 - 4) * 1) / 2", 2},
+			{"50 / 2 * 2 + 10 - 5", 55},
+			{"5 * (2 + 10)", 60},
+			{"0xFF & 0b1010", 10},
+			{"0o777 + 1_000", 1511},
+			{"0x_7fff_ffff_ffff_ffff", 9223372036854775807},
+		},
+	},
+	{
+		Name: "FloatExpression",
+		Cases: []Case{
+			{"3.14", 3.14},
+			{"1e-9", 1e-9},
+			{"2.5E+2", 250.0},
+			{"-1.5", -1.5},
+			{"1.5 + 2.25", 3.75},
+			{"0.5 * 4.0", 2.0},
+			{"1 / 4.0", 0.25},
+			{"1 + 0.5", 1.5},
+			{"0.5 - 1", -0.5},
+			{"10 / 4", 2},
+			{"let ratio = fn(a, b) { a * 1.0 / b }; ratio(1, 8) * 100", 12.5},
+			{"0.1 < 0.2", true},
+			{"1 == 1.0", true},
+			{"1.5 > 2", false},
+			{"1.0 != 1", false},
+			{"1.5 + true", Error("type mismatch: FLOAT + BOOLEAN")},
+			{`"a" + 1.5`, Error("type mismatch: STRING + FLOAT")},
+			{"{1.5: 1}", Error("unusable as hash key: FLOAT")},
+		},
+	},
+	{
+		Name: "BigInteger",
+		Cases: []Case{
+			{"0xFFFF_FFFF_FFFF_FFFF", BigInt("18446744073709551615")},
+			{"9223372036854775807 + 1", BigInt("9223372036854775808")},
+			{"-9223372036854775807 - 2", BigInt("-9223372036854775809")},
+			{"9223372036854775807 * 2", BigInt("18446744073709551614")},
+			{"-(-9223372036854775807 - 1)", BigInt("9223372036854775808")},
+			{"(-9223372036854775807 - 1) / -1", BigInt("9223372036854775808")},
+			{"100000000000000000000", BigInt("100000000000000000000")},
+			{"-9223372036854775808", -9223372036854775808},
+			{"100000000000000000000 - 99999999999999999999", 1},
+			{"100000000000000000000 / 10", BigInt("10000000000000000000")},
+			{"100000000000000000000 > 9223372036854775807", true},
+			{"100000000000000000000 == 100000000000000000000", true},
+			{"-100000000000000000000 < 1", true},
+			{"100000000000000000000 * 0.5", 5e19},
+			{"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(25)", BigInt("15511210043330985984000000")},
+			{"[1, 2][100000000000000000000]", nil},
+			{"{100000000000000000000: 1}[100000000000000000000]", 1},
+		},
+	},
+	{
+		Name: "BooleanExpression",
+		Cases: []Case{
+			{"true", true},
+			{"false", false},
+			{"1 < 2", true},
+			{"2 < 1", false},
+			{"1 > 2", false},
+			{"1 == 2", false},
+			{"1 == 1", true},
+			{"1 != 1", false},
+			{"1 != 2", true},
+			{"true == true", true},
+			{"true == false", false},
+			{"true != true", false},
+			{"true != false", true},
+			{"(1 < 2) != false", true},
+			{"1 == true", false},
+		},
+	},
+	{
+		Name: "BangOperator",
+		Cases: []Case{
+			{"!true", false},
+			{"!false", true},
+			{"!5", false},
+			{"!!true", true},
+			{"!!false", false},
+			{"!!5", true},
+			{"!(if (false) { 5 })", true},
+		},
+	},
+	{
+		Name: "IfElseExpression",
+		Cases: []Case{
+			{"if (true) {10}", 10},
+			{"if (false) {10}", nil},
+			{"if (1) {10}", 10},
+			{"if (1 < 2) {10}", 10},
+			{"if (1 > 2) {10}", nil},
+			{"if (1 < 2) {10} else {20}", 10},
+			{"if (1 > 2) {10} else {20}", 20},
+			{"if ((if (false) { 10 })) { 10 } else { 20 }", 20},
+			{"[if (true) {}][0]", nil},
+			{"len([if (true) {}, if (true) { let b = 1; }])", 2},
+			{"let f = fn(x) { x }; f(if (true) {})", nil},
+			{"let f = fn(x) { x }; f(if (true) { while (false) {} })", nil},
+			{"let a = if (true) { let b = 1; }; a == 1", false},
+			{"(if (true) {}) == (if (false) { 1 })", true},
+		},
+	},
+	{
+		Name: "ReturnStatement",
+		Cases: []Case{
+			{"return 10", 10},
+			{"return 10;9;", 10},
+			{"9;return 2 * 5;9;", 10},
+			{"if (10 > 1) { if (10 > 1) {return 10;} return 1;}", 10},
+			{"let f = fn() { if (true) { return 1; } return 2; }; f();", 1},
+		},
+	},
+	{
+		Name: "ErrorHandling",
+		Cases: []Case{
+			{"5 + true;", Error("type mismatch: INTEGER + BOOLEAN")},
+			{"5 + true;5;", Error("type mismatch: INTEGER + BOOLEAN")},
+			{"-true", Error("unknown operator: -BOOLEAN")},
+			{"true + false", Error("unknown operator: BOOLEAN + BOOLEAN")},
+			{"5;true + false;5;", Error("unknown operator: BOOLEAN + BOOLEAN")},
+			{"if(10 > 1) { true + false}", Error("unknown operator: BOOLEAN + BOOLEAN")},
+			{"if (10 > 1) { if (10 > 1) { return true + false; } return 1; }", Error("unknown operator: BOOLEAN + BOOLEAN")},
+			{"if (5 + true) { 10 }", Error("type mismatch: INTEGER + BOOLEAN")},
+			{"true < 1", Error("type mismatch: BOOLEAN < INTEGER")},
+			{"foobar", Error("identifier not found: foobar")},
+			{"let g = fn() { let y = 42; y }; g(); let f = fn() { if (false) { let y = 1 }; y }; f()", Error("identifier not found: y")},
+			{"if (false) { let y = 1 }; puts(y)", Error("identifier not found: y")},
+			{"if (false) { nope }; 1", 1},
+			{"let f = fn() { nope }; f()", Error("identifier not found: nope")},
+			{"let x = 10; let f = fn() { let x = x + 1; x }; f()", 11},
+			{"let len = fn(s) { 0 }; len(\"abc\")", 0},
+			{"let n = len(\"abc\"); let len = 5; n + len", 8},
+			{"let f = fn() { let g = fn() { y }; let r = g(); let y = 1; r }; f()", Error("identifier not found: y")},
+			{`"Hello" - "World"`, Error("unknown operator: STRING - STRING")},
+			{`"Hello" + 1`, Error("type mismatch: STRING + INTEGER")},
+			{"1[0]", Error("index operator not supported: INTEGER")},
+			{`{"name": "Monkey"}[fn(x) { x }];`, Error("unusable as hash key: FUNCTION")},
+			{`{[1]: 2}`, Error("unusable as hash key: ARRAY")},
+			{"5(1)", Error("not a function: INTEGER")},
+			{"let add = fn(x, y) { x + y; }; add(1);", Error("wrong number of arguments: want=2, got=1")},
+			{"let f = fn(x) { x }; f(1 + true);", Error("type mismatch: INTEGER + BOOLEAN")},
+			{"5 / 0", Error("division by zero")},
+			{"let f = fn(x) { 10 / x }; f(0); 1", Error("division by zero")},
+			{"100000000000000000000 / 0", Error("division by zero")},
+			{"1 / 0.0 > 1", true},
+			{"let f = fn(n) { f(n + 1) }; f(0)", Error("stack overflow")},
+			{"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(300)", 300},
+		},
+	},
+	{
+		Name: "LetStatement",
+		Cases: []Case{
+			{"let a = 5; a;", 5},
+			{"let a = 5 * 5; a;", 25},
+			{"let a = 5; let b = a; b;", 5},
+			{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+			{"let a = 1; let a = a + 1; a;", 2},
+			{"let 長さ = 3; let 幅2 = 4; 長さ * 幅2", 12},
+			{`let 挨拶 = "こんにちは"; 挨拶 + "、世界"`, "こんにちは、世界"},
+		},
+	},
+	{
+		Name: "FunctionApplication",
+		Cases: []Case{
+			{"let identity = fn(x) { x; }; identity(5);", 5},
+			{"let identity = fn(x) { return x; }; identity(5);", 5},
+			{"let double = fn(x) { x * 2; }; double(5);", 10},
+			{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
+			{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
+			{"fn(x) { x; }(5)", 5},
+			{"let f = fn() { return 1; }; f(); 2;", 2},
+			{"let f = fn() { let a = 1; let b = 2; a + b }; f();", 3},
+			{"let g = 10; let f = fn(a) { let b = a * 2; b + g }; f(1) + f(2);", 26},
+			{"let quote = fn(x) { x * 2 }; quote(21)", 42},
+		},
+	},
+	{
+		Name: "Closures",
+		Cases: []Case{
+			{"let adder = fn(x) { fn(y) { x + y } }; let addTwo = adder(2); addTwo(3);", 5},
+			{"let a = fn(x) { fn(y) { fn(z) { x + y + z } } }; a(1)(2)(3);", 6},
+			{"let apply = fn(f, x) { f(x) }; apply(fn(x) { x * 3 }, 4);", 12},
+		},
+	},
+	{
+		Name: "RecursiveFunctions",
+		Cases: []Case{
+			{"let fib = fn(x) { if (x < 2) { x } else { fib(x - 1) + fib(x - 2) } }; fib(15);", 610},
+			{"let wrapper = fn() { let countDown = fn(x) { if (x == 0) { return 0; } countDown(x - 1) }; countDown(3) }; wrapper();", 0},
+			{"let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } }; let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } }; isEven(10)", true},
+			{"let f = fn() { let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } }; let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } }; isOdd(7) }; f()", true},
+			{"let g = fn() { h() }; let h = fn() { 5 }; g()", 5},
+		},
+	},
+	{
+		Name: "Loops",
+		Cases: []Case{
+			{"let i = 0; let sum = 0; while (i < 5) { let sum = sum + i; let i = i + 1; } sum", 10},
+			{"let i = 0; while (i < 10000) { let i = i + 1; } i", 10000},
+			{"let i = 0; while (true) { if (i == 3) { break; } let i = i + 1; } i", 3},
+			{"let i = 0; let odd = 0; while (i < 6) { let i = i + 1; if (i / 2 * 2 == i) { continue; } let odd = odd + i; } odd", 9},
+			{"let sum = 0; for (x in [1, 2, 3]) { let sum = sum + x; } sum", 6},
+			{`let s = ""; for (k in {"b": 1, "a": 2}) { let s = s + k; } s`, "ab"},
+			{`let r = ""; for (c in "abc") { let r = c + r; } r`, "cba"},
+			{"let r = 1; for (x in []) { let r = 2; } r", 1},
+			{"let r = 0; for (x in [1, 2, 3, 4]) { if (x == 3) { break; } let r = r + x; } r", 3},
+			{"let r = 0; for (x in [1, 2, 3, 4]) { if (x == 2) { continue; } let r = r + x; } r", 8},
+			{"let n = 0; for (x in [1, 2, 3]) { for (y in [1, 2, 3]) { if (y > x) { break; } let n = n + 1; } } n", 6},
+			{"let find = fn(arr, t) { for (x in arr) { if (x == t) { return true; } } false }; find([1, 2, 3], 2)", true},
+			{"let find = fn(arr, t) { for (x in arr) { if (x == t) { return true; } } false }; find([1, 2, 3], 5)", false},
+			{"let sum = fn(n) { let i = 0; let s = 0; while (i < n) { let i = i + 1; let s = s + i; } s }; sum(100)", 5050},
+			{"let f = fn() { while (false) { } }; f()", nil},
+			{"let f = fn(arr) { let out = []; for (x in arr) { let out = push(out, x * 2); } out }; f([1, 2, 3])", []int{2, 4, 6}},
+			{"let i = 0; while (true) { i += 1; let x = if (i > 3) { break; }; } i", 4},
+			{"let i = 0; let s = 0; while (i < 5) { i += 1; s += if (i == 2) { continue; } else { i }; } s", 13},
+			{"let r = 0; for (x in [1, 2]) { r = r + (if (true) { break; }); } r", 0},
+			{"let f = fn(x) { x }; let n = 0; for (x in [1, 2]) { f(if (x == 1) { continue; } else { x }); n += x; } n", 2},
+			{`let n = 0; for (x in [1, 2]) { let h = {"a": -(if (x == 1) { continue; } else { x })}; n += h["a"]; } n`, -2},
+			{"let i = 0; while (i < 5000) { i += 1; [1, if (true) { continue; }] } i", 5000},
+			{"let n = 0; for (x in [1, 2, 3]) { n += x; 1 + (if (x == 2) { break; } else { 0 }) } n", 3},
+			{"let f = fn() { let i = 0; while (i < 5000) { i += 1; [1, if (true) { continue; }] } i }; f()", 5000},
+			{"for (x in 1) { }", Error("cannot iterate over INTEGER")},
+			{"while (true) { 1 + true; }", Error("type mismatch: INTEGER + BOOLEAN")},
+		},
+	},
+	{
+		Name: "Assignment",
+		Cases: []Case{
+			{"let x = 1; x = 2; x", 2},
+			{"let x = 1; x = x + 1", 2},
+			{"let a = 1; let b = 2; a = b = 5; a + b", 10},
+			{"let x = 10; x += 5; x -= 3; x *= 2; x /= 4; x", 6},
+			{`let s = "a"; s += "b"; s`, "ab"},
+			{"let x = 1.5; x *= 2; x", 3.0},
+			{"let i = 0; let sum = 0; while (i < 5) { sum += i; i += 1; } sum", 10},
+			{"let x = 1; let f = fn() { x = x + 1; }; f(); f(); x", 3},
+			{"let f = fn(n) { n = n * 2; n }; f(21)", 42},
+			{"let mk = fn() { let c = 0; fn() { c += 1; c } }; let g = mk(); g(); g()", 2},
+			{"let mk = fn() { let c = 0; let inc = fn() { c += 1 }; inc(); inc(); c }; mk()", 2},
+			{"let mk = fn(n) { fn() { fn() { n = n * 2 } } }; let g = mk(3)(); g(); g()", 12},
+			{"let mk = fn() { let c = 0; [fn() { c += 1 }, fn() { c }] }; let p = mk(); p[0](); p[0](); p[1]()", 2},
+			{"let mk = fn() { let c = 0; fn() { c += 1 } }; let a = mk(); let b = mk(); a(); a(); b()", 1},
+			{"let f = fn() { f = 1; 2 }; f() + f", 3},
+			{"let f = fn() { f = 5; f }; f()", 5},
+			{"let g = fn() { let f = fn() { f = 5; f }; f() }; g()", 5},
+			{"let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; let g = f; f = fn(n) { 99 }; g(3)", 99},
+			{"let a = [1, 2, 3]; a[0] = 10; a", []int{10, 2, 3}},
+			{"let a = [1, 2, 3]; a[-1] = 30; a", []int{1, 2, 30}},
+			{"let a = [1, 2, 3]; a[1] += 5; a[1]", 7},
+			{"let a = [[1, 2], [3, 4]]; a[1][0] = 9; a[1]", []int{9, 4}},
+			{"let a = [1]; let b = a; b[0] = 2; a", []int{2}},
+			{"let a = [0, 0]; let i = 0; for (x in [5, 6]) { a[i] = x; i += 1; } a", []int{5, 6}},
+			{`let h = {"a": 1}; h["b"] = 2; h`, map[string]int{"a": 1, "b": 2}},
+			{`let h = {"a": 1}; h["a"] += 10; h["a"]`, 11},
+			{"let a = [1]; a[0] = 5", 5},
+			{"y = 1", Error("assignment to undeclared identifier: y")},
+			{"y += 1", Error("assignment to undeclared identifier: y")},
+			{"len = 1", Error("assignment to undeclared identifier: len")},
+			{"let a = [1]; a[1] = 2", Error("index out of range: 1")},
+			{`let a = [1]; a["x"] = 2`, Error("index assignment not supported: ARRAY[STRING]")},
+			{`let s = "abc"; s[0] = "x"`, Error("index assignment not supported: STRING")},
+			{`let h = {}; h[[1]] = 1`, Error("unusable as hash key: ARRAY")},
+			{"let x = 1; x += true", Error("type mismatch: INTEGER + BOOLEAN")},
+			{`let h = {}; h["a"] += 1`, Error("type mismatch: NULL + INTEGER")},
+		},
+	},
+	{
+		Name: "Operators",
+		Cases: []Case{
+			{"1 <= 2", true},
+			{"2 <= 2", true},
+			{"3 <= 2", false},
+			{"2 >= 3", false},
+			{"2 >= 2", true},
+			{"1.5 <= 1", false},
+			{"1 >= 0.5", true},
+			{"7 % 3", 1},
+			{"-7 % 3", -1},
+			{"7.5 % 2", 1.5},
+			{"1 + 7 % 3 * 2", 3},
+			{"6 & 3", 2},
+			{"6 | 3", 7},
+			{"6 ^ 3", 5},
+			{"~5", -6},
+			{"1 << 10", 1024},
+			{"-16 >> 2", -4},
+			{"1 >> 100", 0},
+			{"1 << 64", BigInt("18446744073709551616")},
+			{"(1 << 64) >> 63", 2},
+			{"~(1 << 64)", BigInt("-18446744073709551617")},
+			{"100000000000000000000 % 7", 2},
+			{"1 | 2 == 3", true},
+			{"1 + 2 & 3", 3},
+			{"1 < 2 && 2 < 3", true},
+			{"1 < 2 && 3 < 2", false},
+			{"false || 1 > 0", true},
+			{"false || false", false},
+			{"true || false && false", true},
+			{"!true || true", true},
+			{"1 && 0", true},
+			{`null_value || "x"`, Error("identifier not found: null_value")},
+			{"false && (1 / 0 == 1)", false},
+			{"true || (1 / 0 == 1)", true},
+			{"let x = 0; true && (x = 5); x", 5},
+			{"let x = 0; false && (x = 5); x", 0},
+			{"true && (1 / 0 == 1)", Error("division by zero")},
+			{"5 % 0", Error("division by zero")},
+			{"1 << -1", Error("negative shift count: -1")},
+			{"1 << 100000", Error("shift count too large: 100000")},
+			{"1.5 & 1", Error("unknown operator: FLOAT & INTEGER")},
+			{"1 << 2.0", Error("unknown operator: INTEGER << FLOAT")},
+			{"true | false", Error("unknown operator: BOOLEAN | BOOLEAN")},
+			{`"a" % "b"`, Error("unknown operator: STRING % STRING")},
+			{"1 & true", Error("type mismatch: INTEGER & BOOLEAN")},
+			{"~1.5", Error("unknown operator: ~FLOAT")},
+			{"~true", Error("unknown operator: ~BOOLEAN")},
+		},
+	},
+	{
+		Name: "Strings",
+		Cases: []Case{
+			{`"Hello World!"`, "Hello World!"},
+			{`"Hello" + " " + "World!"`, "Hello World!"},
+			{`"a" == "a"`, true},
+			{`"a" == "b"`, false},
+			{`"a" != "b"`, true},
+			{`let s = "a"; s + "b" == "ab"`, true},
+		},
+	},
+	{
+		Name: "Arrays",
+		Cases: []Case{
+			{"[]", []int{}},
+			{"[1, 2 * 2, 3 + 3]", []int{1, 4, 6}},
+			{"[1, 2, 3][0]", 1},
+			{"[1, 2, 3][2]", 3},
+			{"let i = 0; [1][i];", 1},
+			{"[1, 2, 3][1 + 1];", 3},
+			{"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
+			{"[1, 2, 3][3]", nil},
+			{"[1, 2, 3][-1]", 3},
+			{"[1, 2, 3][-3]", 1},
+			{"[1, 2, 3][-4]", nil},
+		},
+	},
+	{
+		Name: "Hashes",
+		Cases: []Case{
+			{`{}`, map[string]int{}},
+			{`let two = "two"; {"one": 10 - 9, two: 1 + 1, "thr" + "ee": 6 / 2}`, map[string]int{"one": 1, "two": 2, "three": 3}},
+			{`{"foo": 5}["foo"]`, 5},
+			{`{"foo": 5}["bar"]`, nil},
+			{`let key = "foo"; {"foo": 5}[key]`, 5},
+			{`{}["foo"]`, nil},
+			{`{5: 5}[5]`, 5},
+			{`{true: 5}[true]`, 5},
+			{`{false: 5}[false]`, 5},
+		},
+	},
+	{
+		Name: "Builtins",
+		Cases: []Case{
+			{`len("")`, 0},
+			{`len("four")`, 4},
+			{`len("こんにちは")`, 5},
+			{`len(1)`, Error("argument to `len` not supported, got INTEGER")},
+			{`len("one", "two")`, Error("wrong number of arguments: want=1, got=2")},
+			{`len([1, 2, 3])`, 3},
+			{`len([])`, 0},
+			{`first([1, 2, 3])`, 1},
+			{`first([])`, nil},
+			{`first(1)`, Error("argument to `first` must be ARRAY, got INTEGER")},
+			{`last([1, 2, 3])`, 3},
+			{`last([])`, nil},
+			{`rest([1, 2, 3])`, []int{2, 3}},
+			{`rest([])`, nil},
+			{`push([], 1)`, []int{1}},
+			{`let a = [1]; push(a, 2); a`, []int{1}},
+			{`push(1, 1)`, Error("argument 1 to `push` must be ARRAY, got INTEGER")},
+			{`let len = fn(x) { 42 }; len([])`, 42},
+			{`let map = fn(arr, f) { let iter = fn(arr, acc) { if (len(arr) == 0) { acc } else { iter(rest(arr), push(acc, f(first(arr)))) } }; iter(arr, []) }; map([1, 2, 3], fn(x) { x * 2 })`, []int{2, 4, 6}},
+		},
+	},
+}
+
+//object.ErrorOnOverflowを指定したときのケース
+var ErrorOnOverflowGroups = []Group{
+	{
+		Name: "ErrorOnOverflow",
+		Cases: []Case{
+			{"9223372036854775806 + 1", 9223372036854775807},
+			{"9223372036854775807 + 1", Error("integer overflow: 9223372036854775807 + 1")},
+			{"-9223372036854775807 - 2", Error("integer overflow: -9223372036854775807 - 2")},
+			{"4611686018427387904 * 2", Error("integer overflow: 4611686018427387904 * 2")},
+			{"let min = -9223372036854775807 - 1; -min", Error("integer overflow: -(-9223372036854775808)")},
+			{"let f = fn(x) { x * x }; f(4294967296)", Error("integer overflow: 4294967296 * 4294967296")},
+			{"100000000000000000000 - 1", Error("integer overflow: 100000000000000000000")},
+			{"99999999999999999999", Error("integer overflow: 99999999999999999999")},
+			{"9223372036854775807", 9223372036854775807},
+			{"if (false) { 99999999999999999999 } else { 1 }", 1},
+			{"1 << 62", 4611686018427387904},
+			{"1 << 63", Error("integer overflow: 1 << 63")},
+		},
+	},
+}
+
+//evalは入力を実行した結果を返す。エラーはobject.Errorとして返すこと
+func Run(t *testing.T, eval func(input string) object.Object) {
+	RunGroups(t, Groups, eval)
+}
+
+func RunGroups(t *testing.T, groups []Group, eval func(input string) object.Object) {
+	for _, group := range groups {
+		t.Run(group.Name, func(t *testing.T) {
+			for _, tt := range group.Cases {
+				check(t, tt.Input, eval(tt.Input), tt.Expected)
+			}
+		})
+	}
+}
+
+func check(t *testing.T, input string, actual object.Object, expected interface{}) {
+	t.Helper()
+
+	if errObj, ok := actual.(*object.Error); ok {
+		if _, ok := expected.(Error); !ok {
+			t.Errorf("%q: unexpected error: %s", input, errObj.Message)
+			return
+		}
+	}
+
+	switch expected := expected.(type) {
+	case int:
+		integer, ok := actual.(*object.Integer)
+		if !ok {
+			t.Errorf("%q: object is not Integer. got=%T(%+v)", input, actual, actual)
+			return
+		}
+		if integer.Value != int64(expected) {
+			t.Errorf("%q: wrong value. got=%d, want=%d", input, integer.Value, expected)
+		}
+	case BigInt:
+		bigInt, ok := actual.(*object.BigInt)
+		if !ok {
+			t.Errorf("%q: object is not BigInt. got=%T(%+v)", input, actual, actual)
+			return
+		}
+		if bigInt.Value.String() != string(expected) {
+			t.Errorf("%q: wrong value. got=%s, want=%s", input, bigInt.Value, expected)
+		}
+	case float64:
+		float, ok := actual.(*object.Float)
+		if !ok {
+			t.Errorf("%q: object is not Float. got=%T(%+v)", input, actual, actual)
+			return
+		}
+		if float.Value != expected {
+			t.Errorf("%q: wrong value. got=%g, want=%g", input, float.Value, expected)
+		}
+	case bool:
+		boolean, ok := actual.(*object.Boolean)
+		if !ok {
+			t.Errorf("%q: object is not Boolean. got=%T(%+v)", input, actual, actual)
+			return
+		}
+		if boolean.Value != expected {
+			t.Errorf("%q: wrong value. got=%t, want=%t", input, boolean.Value, expected)
+		}
+	case string:
+		str, ok := actual.(*object.String)
+		if !ok {
+			t.Errorf("%q: object is not String. got=%T(%+v)", input, actual, actual)
+			return
+		}
+		if str.Value != expected {
+			t.Errorf("%q: wrong value. got=%q, want=%q", input, str.Value, expected)
+		}
+	case nil:
+		if _, ok := actual.(*object.Null); !ok {
+			t.Errorf("%q: object is not Null. got=%T(%+v)", input, actual, actual)
+		}
+	case []int:
+		array, ok := actual.(*object.Array)
+		if !ok {
+			t.Errorf("%q: object is not Array. got=%T(%+v)", input, actual, actual)
+			return
+		}
+		if len(array.Elements) != len(expected) {
+			t.Errorf("%q: wrong num of elements. got=%d, want=%d", input, len(array.Elements), len(expected))
+			return
+		}
+		for i, e := range expected {
+			check(t, input, array.Elements[i], e)
+		}
+	case map[string]int:
+		hash, ok := actual.(*object.Hash)
+		if !ok {
+			t.Errorf("%q: object is not Hash. got=%T(%+v)", input, actual, actual)
+			return
+		}
+		if len(hash.Pairs) != len(expected) {
+			t.Errorf("%q: wrong num of pairs. got=%d, want=%d", input, len(hash.Pairs), len(expected))
+			return
+		}
+		for k, v := range expected {
+			pair, ok := hash.Pairs[(&object.String{Value: k}).HashKey()]
+			if !ok {
+				t.Errorf("%q: no pair for key %q", input, k)
+				continue
+			}
+			check(t, input, pair.Value, v)
+		}
+	case Error:
+		errObj, ok := actual.(*object.Error)
+		if !ok {
+			t.Errorf("%q: object is not Error. got=%T(%+v)", input, actual, actual)
+			return
+		}
+		if errObj.Message != string(expected) {
+			t.Errorf("%q: wrong error message. got=%q, want=%q", input, errObj.Message, expected)
+		}
+	default:
+		t.Fatalf("%q: type of expected not handled. got=%T", input, expected)
+	}
+}
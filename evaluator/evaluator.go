@@ -1,9 +1,12 @@
 package evaluator
 
 import (
+	"fmt"
 	"interpreter-go/ast"
 	"interpreter-go/object"
 	"interpreter-go/token"
+	"math"
+	"strings"
 )
 
 var (
@@ -12,49 +15,155 @@ var (
 	FALSE = &object.Boolean{Value: false}
 )
 
-func Eval(node ast.Node) object.Object {
+func Eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
-		return evalProgram(node.Statements)
+		return evalProgram(node.Statements, env)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression)
+		return Eval(node.Expression, env)
 	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			if err := object.CheckBigIntegerLiteral(node.Big, env.OverflowMode()); err != nil {
+				return newError("%s", err)
+			}
+			return &object.BigInt{Value: node.Big}
+		}
 		return &object.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+	case *ast.StringLiteral:
+		return &object.String{Value: node.Value}
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 	case *ast.PrefixExpression:
-		return evalPrefixOperator(node)
+		return evalPrefixOperator(node, env)
 	case *ast.InfixExpression:
-		left := Eval(node.Left)
-		right := Eval(node.Right)
-		return evalInfixExpression(node.Operator, left, right)
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node, env)
+		}
+		left := Eval(node.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+		right := Eval(node.Right, env)
+		if isAbrupt(right) {
+			return right
+		}
+		return evalInfixExpression(node.Operator, left, right, env)
 	case *ast.IfExpression:
-		return evalIfExpression(node)
+		return evalIfExpression(node, env)
 	case *ast.BlockStatement:
-		return evalBlockStatements(node.Statements)
+		return evalBlockStatements(node.Statements, env)
 	case *ast.ReturnStatementNode:
-		val := Eval(node.ReturnValue)
+		val := Eval(node.ReturnValue, env)
+		if isAbrupt(val) {
+			return val
+		}
 		return &object.ReturnValue{Value: val}
+	case *ast.LetStatementNode:
+		val := Eval(node.Value, env)
+		if isAbrupt(val) {
+			return val
+		}
+		env.Set(node.Name.Value, val)
+		return nil
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+	case *ast.BreakStatement:
+		return &object.Break{}
+	case *ast.ContinueStatement:
+		return &object.Continue{}
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
+	case *ast.Identifier:
+		return evalIdentifier(node, env)
+	case *ast.FunctionLiteral:
+		return &object.Function{Parameters: node.Parameters, Body: node.Body, Env: env}
+	case *ast.CallExpression:
+		if isQuoteCall(node, env) {
+			return quote(node.Arguments[0], env)
+		}
+		function := Eval(node.Function, env)
+		if isAbrupt(function) {
+			return function
+		}
+		args := evalExpressions(node.Arguments, env)
+		if len(args) == 1 && isAbrupt(args[0]) {
+			return args[0]
+		}
+		return applyFunction(function, args)
+	case *ast.ArrayLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isAbrupt(elements[0]) {
+			return elements[0]
+		}
+		return &object.Array{Elements: elements}
+	case *ast.IndexExpression:
+		left := Eval(node.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+		index := Eval(node.Index, env)
+		if isAbrupt(index) {
+			return index
+		}
+		return evalIndexExpression(left, index)
+	case *ast.HashLiteral:
+		return evalHashLiteral(node, env)
 	default:
 		return NULL
 	}
 }
 
-func evalProgram(statements []ast.Statement) object.Object {
-	var result object.Object
+//quoteは、マクロの展開中か、quoteという名前の変数がないときだけ特別な形として扱う。
+//let quote = fn(x) {...}で定義した関数は、VMと同じように普通に呼び出す
+func isQuoteCall(node *ast.CallExpression, env *object.Environment) bool {
+	ident, ok := node.Function.(*ast.Identifier)
+	if !ok || ident.Value != "quote" || len(node.Arguments) != 1 {
+		return false
+	}
+	if env.ExpandingMacro() {
+		return true
+	}
+	_, bound := env.Get("quote")
+	return !bound
+}
+
+//評価中にGoのpanicが起きても、プロセスを止めずにエラーとして返す
+func evalProgram(statements []ast.Statement, env *object.Environment) (result object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = object.NewPanicError(r)
+		}
+	}()
+
 	for _, s := range statements {
-		result = Eval(s)
-		if returnVal, ok := result.(*object.ReturnValue); ok {
-			return returnVal.Value
+		result = Eval(s, env)
+		switch result := result.(type) {
+		case *object.ReturnValue:
+			return result.Value
+		case *object.Error:
+			return result
 		}
 	}
 	return result
 }
-func evalBlockStatements(statements []ast.Statement) object.Object {
-	var result object.Object
+
+//空のブロックや、letやループで終わるブロックの値はNULLにする
+func evalBlockStatements(statements []ast.Statement, env *object.Environment) object.Object {
+	var result object.Object = NULL
 	for _, s := range statements {
-		result = Eval(s)
-		if result != NULL && result.Type() == object.RETURN_VALUE_OBJ {
+		result = Eval(s, env)
+		if result == nil {
+			result = NULL
+			continue
+		}
+		rt := result.Type()
+		//return、エラー、break、continueは外側のブロックまで伝播させる
+		if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ ||
+			rt == object.BREAK_OBJ || rt == object.CONTINUE_OBJ {
 			return result
 		}
 	}
@@ -68,97 +177,467 @@ func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	return FALSE
 }
 
-func evalPrefixOperator(prefixOperation *ast.PrefixExpression) object.Object {
-	right := Eval(prefixOperation.Right)
+func evalPrefixOperator(prefixOperation *ast.PrefixExpression, env *object.Environment) object.Object {
+	right := Eval(prefixOperation.Right, env)
+	if isAbrupt(right) {
+		return right
+	}
 	switch prefixOperation.Token.Type {
 	case token.BANG:
 		return evalBangOperatorExpression(right)
 	case token.MINUS:
-		return evalMinusOperatorExpression(right)
+		return evalMinusOperatorExpression(right, env)
+	case token.BIT_NOT:
+		result, err := object.BitNotInteger(right)
+		if err != nil {
+			return newError("%s", err)
+		}
+		return result
 	default:
-		return NULL
+		return newError("unknown operator: %s%s", prefixOperation.Operator, right.Type())
 	}
 }
 
 func evalBangOperatorExpression(right object.Object) object.Object {
-	switch right {
-	case TRUE:
-		return FALSE
-	case FALSE:
-		return TRUE
-	case NULL:
-		return TRUE
+	return nativeBoolToBooleanObject(!isTruthy(right))
+}
+
+func evalMinusOperatorExpression(right object.Object, env *object.Environment) object.Object {
+	switch right := right.(type) {
+	case *object.Integer, *object.BigInt:
+		result, err := object.NegateInteger(right, env.OverflowMode())
+		if err != nil {
+			return newError("%s", err)
+		}
+		return result
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
 	default:
-		return FALSE
+		return newError("unknown operator: -%s", right.Type())
 	}
 }
 
-func evalMinusOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
-		return NULL
+//&&と||は左辺で結果が決まれば右辺を評価しない。結果は常に真偽値にする
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isAbrupt(left) {
+		return left
+	}
+	if isTruthy(left) == (node.Operator == "||") {
+		return nativeBoolToBooleanObject(isTruthy(left))
+	}
+	right := Eval(node.Right, env)
+	if isAbrupt(right) {
+		return right
 	}
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+	return nativeBoolToBooleanObject(isTruthy(right))
 }
 
-func evalInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+func evalInfixExpression(operator string, left object.Object, right object.Object, env *object.Environment) object.Object {
 	switch {
 	case right.Type() == object.INTEGER_OBJ && left.Type() == object.INTEGER_OBJ:
-		return evalIntegerInfixExpression(operator, left, right)
+		return evalIntegerInfixExpression(operator, left, right, env)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
+	case right.Type() == object.STRING_OBJ && left.Type() == object.STRING_OBJ:
+		return evalStringInfixExpression(operator, left, right)
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right) //booleanのobjectのポインター比較
+		return nativeBoolToBooleanObject(object.Equal(left, right))
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right) //booleanのobjectのポインター比較
+		return nativeBoolToBooleanObject(!object.Equal(left, right))
+	case left.Type() != right.Type():
+		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
-		return NULL
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+//int64に収まらない結果は、環境のOverflowModeに従ってBigIntにするかエラーにする
+func evalIntegerInfixExpression(operator string, left object.Object, right object.Object, env *object.Environment) object.Object {
+	switch operator {
+	case "+", "-", "*", "/", "%", "&", "|", "^", "<<", ">>":
+		result, err := object.IntegerArithmetic(operator, left, right, env.OverflowMode())
+		if err != nil {
+			return newError("%s", err)
+		}
+		return result
+	case "<":
+		return nativeBoolToBooleanObject(object.CompareIntegers(left, right) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(object.CompareIntegers(left, right) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(object.CompareIntegers(left, right) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(object.CompareIntegers(left, right) >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(object.CompareIntegers(left, right) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(object.CompareIntegers(left, right) != 0)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
-func evalIntegerInfixExpression(operator string, left object.Object, right object.Object) object.Object {
-	leftVal := left.(*object.Integer).Value
-	rightVal := right.(*object.Integer).Value
+//片方が整数のときは浮動小数点数に変換してから計算する
+func evalFloatInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		return &object.Float{Value: leftVal + rightVal}
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		return &object.Float{Value: leftVal - rightVal}
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		return &object.Float{Value: leftVal * rightVal}
 	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
-		return NULL
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+func toFloat(obj object.Object) float64 {
+	if float, ok := obj.(*object.Float); ok {
+		return float.Value
+	}
+	return object.IntegerToFloat(obj)
+}
+
+func evalStringInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	leftVal := left.(*object.String).Value
+	rightVal := right.(*object.String).Value
+	switch operator {
+	case "+":
+		return &object.String{Value: leftVal + rightVal}
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
-func evalIfExpression(ie *ast.IfExpression) object.Object {
-	condObj := Eval(ie.Condition)
+func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	condObj := Eval(ie.Condition, env)
+	if isAbrupt(condObj) {
+		return condObj
+	}
 	if isTruthy(condObj) {
-		return Eval(ie.Consequence)
+		return Eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative)
+		return Eval(ie.Alternative, env)
 	} else {
 		return NULL
 	}
 }
 
+//ループは文なので、letと同じく値を返さない
+func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condObj := Eval(ws.Condition, env)
+		if isAbrupt(condObj) {
+			return condObj
+		}
+		if !isTruthy(condObj) {
+			return nil
+		}
+		if result, done := evalLoopBody(ws.Body, env); done {
+			return result
+		}
+	}
+}
+
+//ループの変数はletと同じく、今の環境に束縛する
+func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := Eval(fs.Iterable, env)
+	if isAbrupt(iterable) {
+		return iterable
+	}
+	elements, ok := object.Elements(iterable)
+	if !ok {
+		return newError("cannot iterate over %s", iterable.Type())
+	}
+
+	for _, el := range elements {
+		env.Set(fs.Variable.Value, el)
+		if result, done := evalLoopBody(fs.Body, env); done {
+			return result
+		}
+	}
+	return nil
+}
+
+//breakならnil、returnやエラーならその値を返して、ループを終える
+func evalLoopBody(body *ast.BlockStatement, env *object.Environment) (object.Object, bool) {
+	result := Eval(body, env)
+	if result == nil {
+		return nil, false
+	}
+	switch result.Type() {
+	case object.BREAK_OBJ:
+		return nil, true
+	case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+//組み込み関数が返したBooleanやNullはTRUE、FALSE、NULLと別のポインターなので、型で見分ける
 func isTruthy(obj object.Object) bool {
-	switch obj {
-	case TRUE:
-		return true
-	case FALSE:
-		return false
-	case NULL:
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value
+	case *object.Null:
 		return false
 	default:
 		return true
 	}
 }
+
+func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+	if val, ok := env.Get(node.Value); ok {
+		return val
+	}
+	if builtin, ok := object.Builtins.Lookup(node.Value); ok {
+		return builtin
+	}
+	return newError("identifier not found: %s", node.Value)
+}
+
+func evalIndexExpression(left object.Object, index object.Object) object.Object {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.HASH_OBJ:
+		return evalHashIndexExpression(left, index)
+	default:
+		return newError("index operator not supported: %s", left.Type())
+	}
+}
+
+//負のインデックスは末尾から数える。範囲外はNULLを返す
+func evalArrayIndexExpression(array object.Object, index object.Object) object.Object {
+	elements := array.(*object.Array).Elements
+	integer, ok := index.(*object.Integer)
+	if !ok {
+		return NULL //BigIntはどの配列に対しても範囲外
+	}
+	idx := integer.Value
+	length := int64(len(elements))
+
+	if idx < 0 {
+		idx += length
+	}
+	if idx < 0 || idx >= length {
+		return NULL
+	}
+	return elements[idx]
+}
+
+func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+	result := []object.Object{}
+
+	for _, e := range exps {
+		evaluated := Eval(e, env)
+		if isAbrupt(evaluated) {
+			return []object.Object{evaluated}
+		}
+		result = append(result, evaluated)
+	}
+	return result
+}
+
+func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	pairs := map[object.HashKey]object.HashPair{}
+
+	for _, pair := range node.Pairs {
+		key := Eval(pair.Key, env)
+		if isAbrupt(key) {
+			return key
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+
+		value := Eval(pair.Value, env)
+		if isAbrupt(value) {
+			return value
+		}
+
+		pairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: value}
+	}
+
+	return &object.Hash{Pairs: pairs}
+}
+
+func evalHashIndexExpression(hash object.Object, index object.Object) object.Object {
+	hashObject := hash.(*object.Hash)
+
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return newError("unusable as hash key: %s", index.Type())
+	}
+
+	pair, ok := hashObject.Pairs[key.HashKey()]
+	if !ok {
+		return NULL
+	}
+	return pair.Value
+}
+
+//代入式の値は、代入した値になる
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		return evalIdentifierAssignment(target, node, env)
+	case *ast.IndexExpression:
+		return evalIndexAssignment(target, node, env)
+	default:
+		return newError("invalid assignment target: %s", node.Target.String())
+	}
+}
+
+func evalIdentifierAssignment(ident *ast.Identifier, node *ast.AssignExpression, env *object.Environment) object.Object {
+	current, ok := env.Get(ident.Value)
+	if !ok {
+		return newError("assignment to undeclared identifier: %s", ident.Value)
+	}
+
+	val := evalAssignedValue(node, current, env)
+	if isAbrupt(val) {
+		return val
+	}
+
+	env.Assign(ident.Value, val)
+	return val
+}
+
+//配列とハッシュは書き換えた値を共有しているので、同じオブジェクトを参照している変数からも見える
+func evalIndexAssignment(target *ast.IndexExpression, node *ast.AssignExpression, env *object.Environment) object.Object {
+	left := Eval(target.Left, env)
+	if isAbrupt(left) {
+		return left
+	}
+	index := Eval(target.Index, env)
+	if isAbrupt(index) {
+		return index
+	}
+
+	var current object.Object
+	if node.Operator != "=" {
+		current = evalIndexExpression(left, index)
+		if isError(current) {
+			return current
+		}
+	}
+
+	val := evalAssignedValue(node, current, env)
+	if isAbrupt(val) {
+		return val
+	}
+
+	if err := object.SetIndex(left, index, val); err != nil {
+		return newError("%s", err)
+	}
+	return val
+}
+
+//複合代入なら、今の値currentと右辺を演算した結果を返す
+func evalAssignedValue(node *ast.AssignExpression, current object.Object, env *object.Environment) object.Object {
+	val := Eval(node.Value, env)
+	if isAbrupt(val) || node.Operator == "=" {
+		return val
+	}
+	return evalInfixExpression(strings.TrimSuffix(node.Operator, "="), current, val, env)
+}
+
+//関数呼び出しの入れ子の上限。VMのフレーム数の上限(vm.MaxFrames)に合わせる
+const maxCallDepth = 1024
+
+func applyFunction(fn object.Object, args []object.Object) object.Object {
+	switch function := fn.(type) {
+	case *object.Function:
+		if len(args) != len(function.Parameters) {
+			return newError("wrong number of arguments: want=%d, got=%d", len(function.Parameters), len(args))
+		}
+
+		//終わらない再帰でGoのスタックを使い切る前に、エラーにして止める
+		if !function.Env.EnterCall(maxCallDepth) {
+			return newError("stack overflow")
+		}
+		defer function.Env.LeaveCall()
+
+		extendedEnv := extendFunctionEnv(function, args)
+		return unwrapReturnValue(Eval(function.Body, extendedEnv))
+	case *object.Builtin:
+		if result := function.Call(args...); result != nil {
+			return result
+		}
+		return NULL
+	default:
+		return newError("not a function: %s", fn.Type())
+	}
+}
+
+//引数は呼び出し元ではなく、関数が定義された環境を外側に持つ環境に束縛する
+func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+	env := object.NewEnclosedEnvironment(fn.Env)
+
+	for i, param := range fn.Parameters {
+		env.Set(param.Value, args[i])
+	}
+	return env
+}
+
+//returnで関数を抜けた後、外側のブロックまで抜けないようにここで剥がす
+func unwrapReturnValue(obj object.Object) object.Object {
+	if returnValue, ok := obj.(*object.ReturnValue); ok {
+		return returnValue.Value
+	}
+	return obj
+}
+
+func newError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...)}
+}
+
+//エラー、return、break、continueは、式の途中で出てきても残りの評価をやめて外側に伝える
+func isAbrupt(obj object.Object) bool {
+	if obj == nil {
+		return false
+	}
+	switch obj.Type() {
+	case object.ERROR_OBJ, object.RETURN_VALUE_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+		return true
+	}
+	return false
+}
+
+func isError(obj object.Object) bool {
+	if obj != nil {
+		return obj.Type() == object.ERROR_OBJ
+	}
+	return false
+}
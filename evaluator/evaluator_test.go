@@ -1,9 +1,12 @@
 package evaluator
 
 import (
+	"interpreter-go/ast"
+	"interpreter-go/conformance"
 	"interpreter-go/lexer"
 	"interpreter-go/object"
 	"interpreter-go/parser"
+	"strings"
 	"testing"
 )
 
@@ -50,7 +53,7 @@ func TestEvalIntegerExpression(t *testing.T) {
 		},
 		{
 			input:    "(10 - (10 - 4) * 1) / 2",
-			expected: 3,
+			expected: 2,
 		},
 	}
 
@@ -270,7 +273,55 @@ func TestErrorHandling(t *testing.T) {
 		},
 		{
 			input:           "if(10 > 1) { true + false}",
-			expectedMessage: "unknown operator: INTEGER + BOOLEAN",
+			expectedMessage: "unknown operator: BOOLEAN + BOOLEAN",
+		},
+		{
+			input:           "if (10 > 1) { if (10 > 1) { return true + false; } return 1; }",
+			expectedMessage: "unknown operator: BOOLEAN + BOOLEAN",
+		},
+		{
+			input:           "-true + 5",
+			expectedMessage: "unknown operator: -BOOLEAN",
+		},
+		{
+			input:           "if (5 + true) { 10 }",
+			expectedMessage: "type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			input:           "let x = 5 + true; x;",
+			expectedMessage: "type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			input:           "let f = fn(x) { x }; f(1 + true);",
+			expectedMessage: "type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			input:           "let f = fn() { foo; 1 }; f();",
+			expectedMessage: "identifier not found: foo",
+		},
+		{
+			input:           `"Hello" - "World"`,
+			expectedMessage: "unknown operator: STRING - STRING",
+		},
+		{
+			input:           `"Hello" + 1`,
+			expectedMessage: "type mismatch: STRING + INTEGER",
+		},
+		{
+			input:           "1[0]",
+			expectedMessage: "index operator not supported: INTEGER",
+		},
+		{
+			input:           `{"name": "Monkey"}[fn(x) { x }];`,
+			expectedMessage: "unusable as hash key: FUNCTION",
+		},
+		{
+			input:           `{[1]: 2}`,
+			expectedMessage: "unusable as hash key: ARRAY",
+		},
+		{
+			input:           "5(1)",
+			expectedMessage: "not a function: INTEGER",
 		},
 	}
 	for _, tt := range tests {
@@ -288,12 +339,495 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+func TestLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{
+			input:    "let a = 5; a;",
+			expected: 5,
+		},
+		{
+			input:    "let a = 5 * 5; a;",
+			expected: 25,
+		},
+		{
+			input:    "let a = 5; let b = a; b;",
+			expected: 5,
+		},
+		{
+			input:    "let a = 5; let b = a; let c = a + b + 5; c;",
+			expected: 15,
+		},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestIdentifierNotFound(t *testing.T) {
+	evaluated := testEval("foobar")
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	if errObj.Message != "identifier not found: foobar" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestFunctionObject(t *testing.T) {
+	input := "fn(x) { x + 2; };"
+
+	evaluated := testEval(input)
+	fn, ok := evaluated.(*object.Function)
+	if !ok {
+		t.Fatalf("object is not Function. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	if len(fn.Parameters) != 1 {
+		t.Fatalf("function has wrong parameters. got=%+v", fn.Parameters)
+	}
+
+	if fn.Parameters[0].String() != "x" {
+		t.Fatalf("parameter is not 'x'. got=%q", fn.Parameters[0])
+	}
+
+	if fn.Body.String() != "(x + 2)" {
+		t.Fatalf("body is not %q. got=%q", "(x + 2)", fn.Body.String())
+	}
+}
+
+func TestFunctionApplication(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{
+			input:    "let identity = fn(x) { x; }; identity(5);",
+			expected: 5,
+		},
+		{
+			input:    "let identity = fn(x) { return x; }; identity(5);",
+			expected: 5,
+		},
+		{
+			input:    "let double = fn(x) { x * 2; }; double(5);",
+			expected: 10,
+		},
+		{
+			input:    "let add = fn(x, y) { x + y; }; add(5, 5);",
+			expected: 10,
+		},
+		{
+			input:    "let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));",
+			expected: 20,
+		},
+		{
+			input:    "fn(x) { x; }(5)",
+			expected: 5,
+		},
+		{
+			input:    "let f = fn() { return 1; }; f(); 2;",
+			expected: 2,
+		},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestClosures(t *testing.T) {
+	input := `
+let adder = fn(x) { fn(y) { x + y } };
+let addTwo = adder(2);
+addTwo(3);`
+
+	testIntegerObject(t, testEval(input), 5)
+}
+
+func TestFunctionArity(t *testing.T) {
+	evaluated := testEval("let add = fn(x, y) { x + y; }; add(1);")
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	if errObj.Message != "wrong number of arguments: want=2, got=1" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestStringLiteral(t *testing.T) {
+	evaluated := testEval(`"Hello World!"`)
+
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	if str.Value != "Hello World!" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
+
+func TestStringConcatenation(t *testing.T) {
+	evaluated := testEval(`"Hello" + " " + "World!"`)
+
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	if str.Value != "Hello World!" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
+
+func TestStringComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{
+			input:    `"a" == "a"`,
+			expected: true,
+		},
+		{
+			input:    `"a" == "b"`,
+			expected: false,
+		},
+		{
+			input:    `"a" != "b"`,
+			expected: true,
+		},
+		{
+			input:    `let s = "a"; s + "b" == "ab"`,
+			expected: true,
+		},
+	}
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	evaluated := testEval("[1, 2 * 2, 3 + 3]")
+
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	if len(result.Elements) != 3 {
+		t.Fatalf("array has wrong num of elements. got=%d", len(result.Elements))
+	}
+
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
+}
+
+func TestArrayIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{
+			input:    "[1, 2, 3][0]",
+			expected: 1,
+		},
+		{
+			input:    "[1, 2, 3][2]",
+			expected: 3,
+		},
+		{
+			input:    "let i = 0; [1][i];",
+			expected: 1,
+		},
+		{
+			input:    "[1, 2, 3][1 + 1];",
+			expected: 3,
+		},
+		{
+			input:    "let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];",
+			expected: 6,
+		},
+		{
+			input:    "[1, 2, 3][3]",
+			expected: nil,
+		},
+		{
+			input:    "[1, 2, 3][-1]",
+			expected: 3,
+		},
+		{
+			input:    "[1, 2, 3][-3]",
+			expected: 1,
+		},
+		{
+			input:    "[1, 2, 3][-4]",
+			expected: nil,
+		},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{input: `len("")`, expected: 0},
+		{input: `len("four")`, expected: 4},
+		{input: `len("こんにちは")`, expected: 5},
+		{input: `len(1)`, expected: "argument to `len` not supported, got INTEGER"},
+		{input: `len("one", "two")`, expected: "wrong number of arguments: want=1, got=2"},
+		{input: `len([1, 2, 3])`, expected: 3},
+		{input: `len([])`, expected: 0},
+		{input: `first([1, 2, 3])`, expected: 1},
+		{input: `first([])`, expected: nil},
+		{input: `first(1)`, expected: "argument to `first` must be ARRAY, got INTEGER"},
+		{input: `last([1, 2, 3])`, expected: 3},
+		{input: `last([])`, expected: nil},
+		{input: `last(1)`, expected: "argument to `last` must be ARRAY, got INTEGER"},
+		{input: `rest([1, 2, 3])`, expected: []int{2, 3}},
+		{input: `rest([])`, expected: nil},
+		{input: `push([], 1)`, expected: []int{1}},
+		{input: `let a = [1]; push(a, 2); a`, expected: []int{1}},
+		{input: `push(1, 1)`, expected: "argument 1 to `push` must be ARRAY, got INTEGER"},
+		{input: `push([])`, expected: "wrong number of arguments: want=2, got=1"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("obj not Array. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(array.Elements) != len(expected) {
+				t.Errorf("wrong num of elements. want=%d, got=%d", len(expected), len(array.Elements))
+				continue
+			}
+			for i, expectedElem := range expected {
+				testIntegerObject(t, array.Elements[i], int64(expectedElem))
+			}
+		}
+	}
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+{
+	"one": 10 - 9,
+	two: 1 + 1,
+	"thr" + "ee": 6 / 2,
+	4: 4,
+	true: 5,
+	false: 6
+}`
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	expected := map[object.HashKey]int64{
+		(&object.String{Value: "one"}).HashKey():   1,
+		(&object.String{Value: "two"}).HashKey():   2,
+		(&object.String{Value: "three"}).HashKey(): 3,
+		(&object.Integer{Value: 4}).HashKey():      4,
+		TRUE.HashKey():                             5,
+		FALSE.HashKey():                            6,
+	}
+
+	if len(result.Pairs) != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	}
+
+	for expectedKey, expectedValue := range expected {
+		pair, ok := result.Pairs[expectedKey]
+		if !ok {
+			t.Errorf("no pair for given key in Pairs")
+		}
+		testIntegerObject(t, pair.Value, expectedValue)
+	}
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{
+			input:    `{"foo": 5}["foo"]`,
+			expected: 5,
+		},
+		{
+			input:    `{"foo": 5}["bar"]`,
+			expected: nil,
+		},
+		{
+			input:    `let key = "foo"; {"foo": 5}[key]`,
+			expected: 5,
+		},
+		{
+			input:    `{}["foo"]`,
+			expected: nil,
+		},
+		{
+			input:    `{5: 5}[5]`,
+			expected: 5,
+		},
+		{
+			input:    `{true: 5}[true]`,
+			expected: 5,
+		},
+		{
+			input:    `{false: 5}[false]`,
+			expected: 5,
+		},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestRegisteredBuiltin(t *testing.T) {
+	err := object.RegisterBuiltin("repeat", 2, []object.ObjectType{object.STRING_OBJ, object.INTEGER_OBJ},
+		func(args ...object.Object) object.Object {
+			str := args[0].(*object.String).Value
+			result := ""
+			for i := int64(0); i < args[1].(*object.Integer).Value; i++ {
+				result += str
+			}
+			return &object.String{Value: result}
+		})
+	if err != nil {
+		t.Fatalf("RegisterBuiltin returned error: %s", err)
+	}
+
+	evaluated := testEval(`let s = "ab"; repeat(s, 3)`)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T(%+v)", evaluated, evaluated)
+	}
+	if str.Value != "ababab" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+
+	evaluated = testEval(`repeat("ab", "3")`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "argument 2 to `repeat` must be INTEGER, got STRING" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+
+	//環境の束縛が組み込み関数より優先される
+	testIntegerObject(t, testEval(`let repeat = 1; repeat`), 1)
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, testEval)
+}
+
+func TestAssignCapturedVariable(t *testing.T) {
+	//評価器ではクロージャが外側の環境を共有するので、捕まえた変数も書き換えられる
+	input := `
+	let counter = fn() { let c = 0; fn() { c += 1; c } };
+	let next = counter();
+	next();
+	next();
+	`
+	testIntegerObject(t, testEval(input), 2)
+}
+
+func TestRecoverFromPanic(t *testing.T) {
+	//構文エラーの後に残るようなnilの子ノードを評価させる
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.ExpressionStatement{Expression: (*ast.Identifier)(nil)},
+		},
+	}
+
+	evaluated := Eval(program, object.NewEnvironment())
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T(%+v)", evaluated, evaluated)
+	}
+	if !strings.HasPrefix(errObj.Message, "internal error: ") {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+	if (errObj.Stack != "") != object.Debug {
+		t.Errorf("stack is attached only in debug builds. debug=%t, stack=%q", object.Debug, errObj.Stack)
+	}
+}
+
+func TestErrorOnOverflow(t *testing.T) {
+	conformance.RunGroups(t, conformance.ErrorOnOverflowGroups, func(input string) object.Object {
+		return testEvalWithMode(input, object.ErrorOnOverflow)
+	})
+}
+
+func TestOverflowModeInEnclosedEnvironment(t *testing.T) {
+	env := object.NewEnvironment()
+	enclosed := object.NewEnclosedEnvironment(env)
+	enclosed.SetOverflowMode(object.ErrorOnOverflow)
+
+	if env.OverflowMode() != object.ErrorOnOverflow {
+		t.Errorf("overflow mode is not shared with the outer environment")
+	}
+}
+
 func testEval(input string) object.Object {
+	return testEvalWithMode(input, object.PromoteOnOverflow)
+}
+
+func testEvalWithMode(input string, mode object.OverflowMode) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
+	env := object.NewEnvironment()
+	env.SetOverflowMode(mode)
 
-	return Eval(program)
+	return Eval(program, env)
 }
 
 func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
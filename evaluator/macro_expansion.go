@@ -0,0 +1,136 @@
+package evaluator
+
+import (
+	"fmt"
+	"interpreter-go/ast"
+	"interpreter-go/object"
+)
+
+//トップレベルの let <name> = macro(...) {...}; をenvに登録して、programから取り除く
+func DefineMacros(program *ast.Program, env *object.Environment) {
+	definitions := []int{}
+
+	for i, statement := range program.Statements {
+		if isMacroDefinition(statement) {
+			addMacro(statement, env)
+			definitions = append(definitions, i)
+		}
+	}
+
+	for i := len(definitions) - 1; i >= 0; i = i - 1 {
+		definitionIndex := definitions[i]
+		program.Statements = append(
+			program.Statements[:definitionIndex],
+			program.Statements[definitionIndex+1:]...,
+		)
+	}
+}
+
+func isMacroDefinition(node ast.Statement) bool {
+	letStatement, ok := node.(*ast.LetStatementNode)
+	if !ok {
+		return false
+	}
+
+	_, ok = letStatement.Value.(*ast.MacroLiteral)
+	return ok
+}
+
+func addMacro(stmt ast.Statement, env *object.Environment) {
+	letStatement := stmt.(*ast.LetStatementNode)
+	macroLiteral := letStatement.Value.(*ast.MacroLiteral)
+
+	macro := &object.Macro{
+		Parameters: macroLiteral.Parameters,
+		Env:        env,
+		Body:       macroLiteral.Body,
+	}
+
+	env.Set(letStatement.Name.Value, macro)
+}
+
+//マクロ呼び出しを、マクロが返したASTに置き換える。
+//構文解析の後、EvalやCompileの前に呼び出す
+func ExpandMacros(program ast.Node, env *object.Environment) (expanded ast.Node, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			expanded, err = program, object.NewPanicError(r)
+		}
+	}()
+
+	expanded = ast.Modify(program, func(node ast.Node) ast.Node {
+		if err != nil {
+			return node
+		}
+
+		callExpression, ok := node.(*ast.CallExpression)
+		if !ok {
+			return node
+		}
+
+		macro, ok := isMacroCall(callExpression, env)
+		if !ok {
+			return node
+		}
+
+		if len(callExpression.Arguments) != len(macro.Parameters) {
+			err = fmt.Errorf("wrong number of arguments to macro: want=%d, got=%d",
+				len(macro.Parameters), len(callExpression.Arguments))
+			return node
+		}
+
+		args := quoteArgs(callExpression)
+		evalEnv := extendMacroEnv(macro, args)
+
+		evaluated := Eval(macro.Body, evalEnv)
+		evaluated = unwrapReturnValue(evaluated)
+
+		switch evaluated := evaluated.(type) {
+		case *object.Quote:
+			return evaluated.Node
+		case *object.Error:
+			err = fmt.Errorf("%s", evaluated.Message)
+		default:
+			err = fmt.Errorf("macro must return a quoted AST node")
+		}
+		return node
+	})
+
+	return expanded, err
+}
+
+func isMacroCall(exp *ast.CallExpression, env *object.Environment) (*object.Macro, bool) {
+	identifier, ok := exp.Function.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+
+	obj, ok := env.Get(identifier.Value)
+	if !ok {
+		return nil, false
+	}
+
+	macro, ok := obj.(*object.Macro)
+	return macro, ok
+}
+
+//マクロの引数は評価せずにASTのまま渡す
+func quoteArgs(exp *ast.CallExpression) []*object.Quote {
+	args := []*object.Quote{}
+
+	for _, a := range exp.Arguments {
+		args = append(args, &object.Quote{Node: a})
+	}
+
+	return args
+}
+
+func extendMacroEnv(macro *object.Macro, args []*object.Quote) *object.Environment {
+	extended := object.NewMacroEnvironment(macro.Env)
+
+	for paramIdx, param := range macro.Parameters {
+		extended.Set(param.Value, args[paramIdx])
+	}
+
+	return extended
+}
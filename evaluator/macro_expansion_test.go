@@ -0,0 +1,244 @@
+package evaluator
+
+import (
+	"interpreter-go/ast"
+	"interpreter-go/lexer"
+	"interpreter-go/object"
+	"interpreter-go/parser"
+	"testing"
+)
+
+func TestDefineMacros(t *testing.T) {
+	input := `
+	let number = 1;
+	let function = fn(x, y) { x + y };
+	let mymacro = macro(x, y) { x + y; };
+	`
+
+	env := object.NewEnvironment()
+	program := testParseProgram(input)
+
+	DefineMacros(program, env)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("Wrong number of statements. got=%d", len(program.Statements))
+	}
+
+	if _, ok := env.Get("number"); ok {
+		t.Fatalf("number should not be defined")
+	}
+	if _, ok := env.Get("function"); ok {
+		t.Fatalf("function should not be defined")
+	}
+
+	obj, ok := env.Get("mymacro")
+	if !ok {
+		t.Fatalf("macro not in environment.")
+	}
+
+	macro, ok := obj.(*object.Macro)
+	if !ok {
+		t.Fatalf("object is not Macro. got=%T (%+v)", obj, obj)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("Wrong number of macro parameters. got=%d", len(macro.Parameters))
+	}
+
+	if macro.Parameters[0].String() != "x" || macro.Parameters[1].String() != "y" {
+		t.Fatalf("parameters wrong. got=%v", macro.Parameters)
+	}
+
+	if macro.Body.String() != "(x + y)" {
+		t.Fatalf("body is not %q. got=%q", "(x + y)", macro.Body.String())
+	}
+}
+
+func TestExpandMacros(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`
+			let infixExpression = macro() { quote(1 + 2); };
+
+			infixExpression();
+			`,
+			`(1 + 2)`,
+		},
+		{
+			`
+			let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); };
+
+			reverse(2 + 2, 10 - 5);
+			`,
+			`(10 - 5) - (2 + 2)`,
+		},
+		{
+			`
+			let unless = macro(condition, consequence, alternative) {
+				quote(if (!(unquote(condition))) {
+					unquote(consequence);
+				} else {
+					unquote(alternative);
+				});
+			};
+
+			unless(10 > 5, puts("not greater"), puts("greater"));
+			`,
+			`if (!(10 > 5)) { puts("not greater") } else { puts("greater") }`,
+		},
+		{
+			//マクロの展開中は、quoteという名前の変数があってもquoteの特別な形になる
+			`
+			let m = macro() { let quote = fn(x) { x }; quote(1 + 2) };
+
+			m();
+			`,
+			`(1 + 2)`,
+		},
+	}
+
+	for _, tt := range tests {
+		expected := testParseProgram(tt.input)
+		program := testParseProgram(tt.input)
+
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+		expanded, err := ExpandMacros(program, env)
+		if err != nil {
+			t.Fatalf("ExpandMacros returned error: %s", err)
+		}
+
+		expected = testParseProgram(tt.expected)
+		if expanded.String() != expected.String() {
+			t.Errorf("not equal. want=%q, got=%q", expected.String(), expanded.String())
+		}
+	}
+}
+
+func TestExpandMacrosTwice(t *testing.T) {
+	input := `
+	let double = macro(x) { quote(unquote(x) * 2); };
+	double(1) + double(3);
+	`
+
+	program := testParseProgram(input)
+	env := object.NewEnvironment()
+	DefineMacros(program, env)
+	expanded, err := ExpandMacros(program, env)
+	if err != nil {
+		t.Fatalf("ExpandMacros returned error: %s", err)
+	}
+
+	if expanded.String() != "((1 * 2) + (3 * 2))" {
+		t.Errorf("wrong expansion. got=%q", expanded.String())
+	}
+}
+
+func TestMacroHygiene(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{
+			//マクロの中のletが、呼び出し側のtmpを上書きしない
+			`
+			let double = macro(x) { quote(if (true) { let tmp = unquote(x); tmp + tmp }) };
+			let tmp = 10;
+			let result = double(tmp + 1);
+			result + tmp;
+			`,
+			32,
+		},
+		{
+			//マクロの中の引数名が、埋め込んだ式のyを捕まえない
+			`
+			let addOne = macro(x) { quote(fn(y) { unquote(x) + y }(1)) };
+			let y = 100;
+			addOne(y);
+			`,
+			101,
+		},
+		{
+			//名前を付け替えたletの関数も、自分の名前で再帰呼び出しできる
+			`
+			let m = macro(x) { quote(fn() { let f = fn(n) { if (n == 0) { unquote(x) } else { f(n - 1) } }; f(3) }()) };
+			m(7);
+			`,
+			7,
+		},
+		{
+			//関数の引数と同じ名前でも、関数の外で参照した展開先の変数は付け替えない
+			`
+			let x = 10;
+			let m = macro() { quote(fn(x) { x }(1) + x) };
+			m();
+			`,
+			11,
+		},
+		{
+			//letより前の参照は展開先の変数を指し、内側の関数からは後のletも見える
+			`
+			let n = 5;
+			let m = macro() { quote(fn() { let n = n + 1; let g = fn() { h() }; let h = fn() { n }; g() }()) };
+			m();
+			`,
+			6,
+		},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(tt.input)
+		macroEnv := object.NewEnvironment()
+		DefineMacros(program, macroEnv)
+		expanded, err := ExpandMacros(program, macroEnv)
+		if err != nil {
+			t.Fatalf("ExpandMacros returned error: %s", err)
+		}
+
+		testIntegerObject(t, Eval(expanded, object.NewEnvironment()), tt.expected)
+	}
+}
+
+func TestExpandMacrosErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`let m = macro(x) { quote(unquote(x)) }; m(1, 2);`,
+			"wrong number of arguments to macro: want=1, got=2",
+		},
+		{
+			`let m = macro() { 1 }; m();`,
+			"macro must return a quoted AST node",
+		},
+		{
+			`let m = macro() { quote(unquote(foo)) }; m();`,
+			"identifier not found: foo",
+		},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(tt.input)
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+
+		_, err := ExpandMacros(program, env)
+		if err == nil {
+			t.Errorf("expected error for %q", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func testParseProgram(input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram()
+}
@@ -0,0 +1,254 @@
+package evaluator
+
+import (
+	"fmt"
+	"interpreter-go/ast"
+	"interpreter-go/object"
+	"interpreter-go/token"
+)
+
+func quote(node ast.Node, env *object.Environment) object.Object {
+	node, spliced, err := evalUnquoteCalls(node, env)
+	if err != nil {
+		return err
+	}
+
+	if env.ExpandingMacro() {
+		node = renameBindings(node, spliced, env)
+	}
+	return &object.Quote{Node: node}
+}
+
+//unquote(...)の引数を評価して、その結果のASTに置き換える。
+//埋め込んだASTに含まれる識別子も返す
+func evalUnquoteCalls(quoted ast.Node, env *object.Environment) (ast.Node, map[*ast.Identifier]bool, *object.Error) {
+	spliced := map[*ast.Identifier]bool{}
+	var err *object.Error
+
+	node := ast.Modify(quoted, func(node ast.Node) ast.Node {
+		if !isUnquoteCall(node) || err != nil {
+			return node
+		}
+
+		call := node.(*ast.CallExpression)
+		if len(call.Arguments) != 1 {
+			return node
+		}
+
+		unquoted := Eval(call.Arguments[0], env)
+		if isError(unquoted) {
+			err = unquoted.(*object.Error)
+			return node
+		}
+
+		converted, ok := convertObjectToASTNode(unquoted)
+		if !ok {
+			err = newError("cannot unquote %s", unquoted.Type())
+			return node
+		}
+
+		ast.Modify(converted, func(n ast.Node) ast.Node {
+			if ident, ok := n.(*ast.Identifier); ok {
+				spliced[ident] = true
+			}
+			return n
+		})
+		return converted
+	})
+
+	return node, spliced, err
+}
+
+func isUnquoteCall(node ast.Node) bool {
+	callExpression, ok := node.(*ast.CallExpression)
+	if !ok {
+		return false
+	}
+
+	ident, ok := callExpression.Function.(*ast.Identifier)
+	return ok && ident.Value == "unquote"
+}
+
+func convertObjectToASTNode(obj object.Object) (ast.Node, bool) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		t := token.Token{Type: token.INT, Literal: fmt.Sprintf("%d", obj.Value)}
+		return &ast.IntegerLiteral{Token: t, Value: obj.Value}, true
+	case *object.BigInt:
+		t := token.Token{Type: token.INT, Literal: obj.Inspect()}
+		return &ast.IntegerLiteral{Token: t, Big: obj.Value}, true
+	case *object.Float:
+		t := token.Token{Type: token.FLOAT, Literal: obj.Inspect()}
+		return &ast.FloatLiteral{Token: t, Value: obj.Value}, true
+	case *object.Boolean:
+		var t token.Token
+		if obj.Value {
+			t = token.Token{Type: token.TRUE, Literal: "true"}
+		} else {
+			t = token.Token{Type: token.FALSE, Literal: "false"}
+		}
+		return &ast.Boolean{Token: t, Value: obj.Value}, true
+	case *object.String:
+		t := token.Token{Type: token.STRING, Literal: obj.Value}
+		return &ast.StringLiteral{Token: t, Value: obj.Value}, true
+	case *object.Quote:
+		return obj.Node, true
+	default:
+		return nil, false
+	}
+}
+
+//マクロの中で書いたletや引数、forの変数の名前を、展開ごとに別の名前に付け替える。
+//これで展開先のコードの変数と衝突しない。unquoteで埋め込んだ識別子はそのままにする。
+//番号はenvの一番外側の環境で数えるので、REPLの前の入力で展開した名前とも衝突しない
+func renameBindings(node ast.Node, spliced map[*ast.Identifier]bool, env *object.Environment) ast.Node {
+	r := &bindingRenamer{spliced: spliced, renamed: map[*ast.Identifier]bool{}}
+	r.walk(node, newMacroScope(nil))
+	for len(r.functions) > 0 {
+		fn := r.functions[0]
+		r.functions = r.functions[1:]
+		r.walkFunction(fn)
+	}
+	if len(r.renamed) == 0 {
+		return node
+	}
+
+	suffix := fmt.Sprintf("#%d", env.NextGensym())
+
+	return ast.Modify(node, func(n ast.Node) ast.Node {
+		switch n := n.(type) {
+		case *ast.Identifier:
+			if !r.renamed[n] {
+				return n
+			}
+			renamed := *n
+			renamed.Value = n.Value + suffix
+			return &renamed
+		case *ast.LetStatementNode:
+			//let f = fn() {...}の関数は自分の名前fで再帰呼び出しするので、letの名前と合わせる。
+			//Modifyが作ったコピーなので、元のマクロの本体は変わらない
+			if fn, ok := n.Value.(*ast.FunctionLiteral); ok && fn.Name != "" && fn.Name+suffix == n.Name.Value {
+				fn.Name = n.Name.Value
+			}
+		}
+		return n
+	})
+}
+
+//マクロの中で束縛した名前。評価器と同じく、関数リテラルごとにスコープを作る
+type macroScope struct {
+	bound map[string]bool
+	outer *macroScope
+}
+
+func newMacroScope(outer *macroScope) *macroScope {
+	return &macroScope{bound: map[string]bool{}, outer: outer}
+}
+
+func (s *macroScope) resolves(name string) bool {
+	for scope := s; scope != nil; scope = scope.outer {
+		if scope.bound[name] {
+			return true
+		}
+	}
+	return false
+}
+
+type pendingFunction struct {
+	parameters []*ast.Identifier
+	body       *ast.BlockStatement
+	scope      *macroScope //関数リテラルを書いたスコープ
+}
+
+//マクロが束縛した名前を指す識別子だけを集める。quote(fn(x) { x } + x)の外側のxのように、
+//展開先の変数を指す識別子は付け替えない
+type bindingRenamer struct {
+	spliced   map[*ast.Identifier]bool
+	renamed   map[*ast.Identifier]bool
+	functions []pendingFunction //外側のスコープを最後まで見てから調べる関数リテラル
+}
+
+//同じスコープでは書いた順に束縛するので、let x = x + 1の右辺のxは外側のxを指す。
+//関数の本体は呼び出したときに評価するので、外側のスコープの後のletも見えるように、後で調べる
+func (r *bindingRenamer) walk(node ast.Node, scope *macroScope) {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		if !r.spliced[node] && scope.resolves(node.Value) {
+			r.renamed[node] = true
+		}
+	case *ast.LetStatementNode:
+		r.walk(node.Value, scope)
+		r.bind(node.Name, scope)
+	case *ast.ForStatement:
+		r.walk(node.Iterable, scope)
+		r.bind(node.Variable, scope)
+		r.walk(node.Body, scope)
+	case *ast.FunctionLiteral:
+		r.functions = append(r.functions, pendingFunction{node.Parameters, node.Body, scope})
+	case *ast.MacroLiteral:
+		r.functions = append(r.functions, pendingFunction{node.Parameters, node.Body, scope})
+	case *ast.Program:
+		for _, s := range node.Statements {
+			r.walk(s, scope)
+		}
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			r.walk(s, scope)
+		}
+	case *ast.ExpressionStatement:
+		r.walk(node.Expression, scope)
+	case *ast.ReturnStatementNode:
+		r.walk(node.ReturnValue, scope)
+	case *ast.WhileStatement:
+		r.walk(node.Condition, scope)
+		r.walk(node.Body, scope)
+	case *ast.IfExpression:
+		r.walk(node.Condition, scope)
+		r.walk(node.Consequence, scope)
+		if node.Alternative != nil {
+			r.walk(node.Alternative, scope)
+		}
+	case *ast.PrefixExpression:
+		r.walk(node.Right, scope)
+	case *ast.InfixExpression:
+		r.walk(node.Left, scope)
+		r.walk(node.Right, scope)
+	case *ast.AssignExpression:
+		r.walk(node.Target, scope)
+		r.walk(node.Value, scope)
+	case *ast.IndexExpression:
+		r.walk(node.Left, scope)
+		r.walk(node.Index, scope)
+	case *ast.CallExpression:
+		r.walk(node.Function, scope)
+		for _, a := range node.Arguments {
+			r.walk(a, scope)
+		}
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			r.walk(el, scope)
+		}
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			r.walk(pair.Key, scope)
+			r.walk(pair.Value, scope)
+		}
+	}
+}
+
+func (r *bindingRenamer) walkFunction(fn pendingFunction) {
+	scope := newMacroScope(fn.scope)
+	for _, p := range fn.parameters {
+		r.bind(p, scope)
+	}
+	r.walk(fn.body, scope)
+}
+
+//unquoteで埋め込んだ名前は、展開先で書いた名前なので束縛しない
+func (r *bindingRenamer) bind(ident *ast.Identifier, scope *macroScope) {
+	if ident == nil || r.spliced[ident] {
+		return
+	}
+	scope.bound[ident.Value] = true
+	r.renamed[ident] = true
+}
@@ -0,0 +1,143 @@
+package evaluator
+
+import (
+	"interpreter-go/object"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    `quote(5)`,
+			expected: `5`,
+		},
+		{
+			input:    `quote(5 + 8)`,
+			expected: `(5 + 8)`,
+		},
+		{
+			input:    `quote(foobar)`,
+			expected: `foobar`,
+		},
+		{
+			input:    `quote(foobar + barfoo)`,
+			expected: `(foobar + barfoo)`,
+		},
+		{
+			input:    `quote(fn(x) { x })`,
+			expected: `fn(x)x`,
+		},
+	}
+
+	for _, tt := range tests {
+		testQuoteObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestQuoteUnquote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    `quote(unquote(4))`,
+			expected: `4`,
+		},
+		{
+			input:    `quote(unquote(4 + 4))`,
+			expected: `8`,
+		},
+		{
+			input:    `quote(8 + unquote(4 + 4))`,
+			expected: `(8 + 8)`,
+		},
+		{
+			input:    `quote(unquote(4 + 4) + 8)`,
+			expected: `(8 + 8)`,
+		},
+		{
+			input:    `let foobar = 8; quote(foobar)`,
+			expected: `foobar`,
+		},
+		{
+			input:    `let foobar = 8; quote(unquote(foobar))`,
+			expected: `8`,
+		},
+		{
+			input:    `quote(unquote(true))`,
+			expected: `true`,
+		},
+		{
+			input:    `quote(unquote(true == false))`,
+			expected: `false`,
+		},
+		{
+			input:    `quote(unquote(quote(4 + 4)))`,
+			expected: `(4 + 4)`,
+		},
+		{
+			input:    `let quotedInfixExpression = quote(4 + 4); quote(unquote(4 + 4) + unquote(quotedInfixExpression))`,
+			expected: `(8 + (4 + 4))`,
+		},
+		{
+			input:    `quote(f(unquote(1 + 1)))`,
+			expected: `f(2)`,
+		},
+		{
+			input:    `quote(unquote("a" + "b"))`,
+			expected: `ab`,
+		},
+	}
+
+	for _, tt := range tests {
+		testQuoteObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestUnquoteErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    `quote(unquote(foo))`,
+			expected: "identifier not found: foo",
+		},
+		{
+			input:    `quote(unquote([1]))`,
+			expected: "cannot unquote ARRAY",
+		},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("expected *object.Error, got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+}
+
+func testQuoteObject(t *testing.T, evaluated object.Object, expected string) {
+	t.Helper()
+
+	quote, ok := evaluated.(*object.Quote)
+	if !ok {
+		t.Fatalf("expected *object.Quote, got=%T(%+v)", evaluated, evaluated)
+	}
+
+	if quote.Node == nil {
+		t.Fatalf("quote.Node is nil")
+	}
+
+	if quote.Node.String() != expected {
+		t.Errorf("not equal. got=%q, want=%q", quote.Node.String(), expected)
+	}
+}
@@ -0,0 +1,578 @@
+package format
+
+import (
+	"bytes"
+	"interpreter-go/ast"
+	"interpreter-go/lexer"
+	"interpreter-go/parser"
+	"interpreter-go/token"
+	"strings"
+	"unicode/utf8"
+)
+
+//Monkeyのソースを決まった形に整形する。
+//ブレースはBracesに従って置き、ブロックの中は1段ずつ字下げする。
+//Widthを超える呼び出し、配列、ハッシュ、引数リストや、要素の間に行コメントや別の行のコメントのあるものは、
+//要素ごとに改行する。
+//括弧は優先順位に必要なものだけを残し、コメントと文の間の空行(1行まで)は残す
+type Config struct {
+	Width  int        //1行の幅の上限
+	Indent int        //1段の字下げの空白の数
+	Braces BraceStyle //ブロックの'{'の置き方
+}
+
+type BraceStyle int
+
+const (
+	//'{'を文や式と同じ行に置く。if (x) {
+	SameLine BraceStyle = iota
+	//'{'を次の行の先頭に置き、elseも'}'の次の行に書く
+	NextLine
+)
+
+var DefaultConfig = Config{Width: 80, Indent: 4}
+
+//構文エラーのあるソースは整形しない
+type SyntaxError struct {
+	Errors []*parser.ParseError
+}
+
+func (e *SyntaxError) Error() string {
+	messages := []string{}
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+//DefaultConfigで整形する
+func Source(src []byte) ([]byte, error) {
+	return DefaultConfig.Source(src)
+}
+
+//先頭の"#!"の行はそのまま残す
+func (c Config) Source(src []byte) ([]byte, error) {
+	source := string(src)
+	shebang := ""
+	if strings.HasPrefix(source, "#!") {
+		body := lexer.StripShebang(source)
+		shebang = source[:len(source)-len(body)]
+		source = body
+	}
+
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.ParseErrors()) != 0 {
+		return nil, &SyntaxError{Errors: p.ParseErrors()}
+	}
+
+	pr := &printer{config: c, source: source, comments: collectComments(source)}
+	if shebang != "" {
+		pr.write(shebang)
+		pr.lastLine = 1
+	}
+	pr.statements(program.Statements, token.Position{}, shebang == "")
+	if pr.buf.Len() > 0 {
+		pr.buf.WriteString("\n")
+	}
+	return pr.buf.Bytes(), nil
+}
+
+func collectComments(source string) []token.Token {
+	comments := []token.Token{}
+	l := lexer.NewWithComments(source)
+	for {
+		tok := l.NextToken()
+		if tok.Type == token.EOF {
+			return comments
+		}
+		if tok.Type == token.COMMENT {
+			comments = append(comments, tok)
+		}
+	}
+}
+
+type printer struct {
+	config   Config
+	source   string
+	buf      bytes.Buffer
+	comments []token.Token //まだ書いていないコメント。ソースの順に並んでいる
+
+	indent     int
+	column     int  //今の行に書いた文字数
+	needIndent bool //次に書くときに字下げする
+	lastLine   int  //最後に書いた文やコメントの、ソース上の最後の行
+}
+
+//listで幅に収まるかを試した後に戻すための状態
+type printerState struct {
+	length     int
+	comments   []token.Token
+	indent     int
+	column     int
+	needIndent bool
+	lastLine   int
+}
+
+func (p *printer) save() printerState {
+	return printerState{p.buf.Len(), p.comments, p.indent, p.column, p.needIndent, p.lastLine}
+}
+
+func (p *printer) restore(s printerState) {
+	p.buf.Truncate(s.length)
+	p.comments, p.indent, p.column, p.needIndent, p.lastLine = s.comments, s.indent, s.column, s.needIndent, s.lastLine
+}
+
+//sを書く。sの中の改行の後は字下げしない(ブロックコメントの中身はそのまま残す)
+func (p *printer) write(s string) {
+	if p.needIndent {
+		p.buf.WriteString(strings.Repeat(" ", p.indent*p.config.Indent))
+		p.column = p.indent * p.config.Indent
+		p.needIndent = false
+	}
+	p.buf.WriteString(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		p.column = utf8.RuneCountInString(s[i+1:])
+	} else {
+		p.column += utf8.RuneCountInString(s)
+	}
+}
+
+//空白だけの行を作らないように、字下げは次に書くときまで遅らせる
+func (p *printer) newline() {
+	p.buf.WriteString("\n")
+	p.column = 0
+	p.needIndent = true
+}
+
+//次の文かコメントをsourceのline行目から書き始める。
+//元のソースで空行があれば、1行だけ空ける。ブロックの先頭では空けない
+func (p *printer) startLine(line int, first bool) {
+	if p.buf.Len() == 0 {
+		return
+	}
+	p.newline()
+	if !first && line > p.lastLine+1 {
+		p.newline()
+	}
+}
+
+//endより前にあるコメントを、1つずつ別の行に書く
+func (p *printer) flushComments(end token.Position, first bool) bool {
+	for len(p.comments) > 0 && (!end.IsValid() || p.comments[0].Pos.Offset < end.Offset) {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		p.startLine(c.Pos.Line, first)
+		p.write(c.Literal)
+		p.lastLine = c.End.Line
+		first = false
+	}
+	return first
+}
+
+//文の中にあったコメントと、文の終わりと同じ行のコメントは、文の後ろに続ける。
+//ブロックの'}'(limit)より後のコメントは、外側の文のものとして残す
+func (p *printer) trailingComments(end, limit token.Position) {
+	for len(p.comments) > 0 && (p.comments[0].Pos.Offset < end.Offset || p.comments[0].Pos.Line == end.Line) {
+		c := p.comments[0]
+		if limit.IsValid() && c.Pos.Offset >= limit.Offset {
+			break
+		}
+		p.comments = p.comments[1:]
+		p.write(" " + c.Literal)
+		end = c.End
+	}
+	p.lastLine = end.Line
+}
+
+//endはブロックの'}'の位置。プログラムの最後ではendを無効な位置にして、残りのコメントを全て書く。
+//firstはブロックの先頭で、最初の文の前に空行を入れないときにtrue
+func (p *printer) statements(statements []ast.Statement, end token.Position, first bool) {
+	for i, stmt := range statements {
+		first = p.flushComments(stmt.Pos(), first)
+		p.startLine(stmt.Pos().Line, first)
+		first = false
+
+		var next ast.Statement
+		if i+1 < len(statements) {
+			next = statements[i+1]
+		}
+		p.statement(stmt, next)
+		p.trailingComments(stmt.End(), end)
+	}
+	p.flushComments(end, first)
+}
+
+//nextは同じブロックの次の文。式文の';'を省けるかの判断に使う
+func (p *printer) statement(stmt ast.Statement, next ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatementNode:
+		p.write("let " + stmt.Name.Value + " = ")
+		p.expression(stmt.Value, parser.LOWEST)
+		p.write(";")
+	case *ast.ReturnStatementNode:
+		p.write("return")
+		if stmt.ReturnValue != nil {
+			p.write(" ")
+			p.expression(stmt.ReturnValue, parser.LOWEST)
+		}
+		p.write(";")
+	case *ast.ExpressionStatement:
+		p.expression(stmt.Expression, parser.LOWEST)
+		if _, ok := stmt.Expression.(*ast.IfExpression); !ok || continuesExpression(next) {
+			p.write(";")
+		}
+	case *ast.WhileStatement:
+		p.write("while (")
+		p.condition(stmt.Pos(), stmt.Condition, stmt.Body.Pos())
+		p.write(")")
+		p.block(stmt.Body)
+	case *ast.ForStatement:
+		p.write("for (" + stmt.Variable.Value + " in ")
+		p.condition(stmt.Pos(), stmt.Iterable, stmt.Body.Pos())
+		p.write(")")
+		p.block(stmt.Body)
+	case *ast.BreakStatement:
+		p.write("break;")
+	case *ast.ContinueStatement:
+		p.write("continue;")
+	}
+}
+
+//if、while、forの括弧の中の式。startから本体の'{'(end)までのコメントが本体に入らないように、
+//式より前のものは式の前に、それ以外は式の後ろに書く。行コメントの後は改行する
+func (p *printer) condition(start token.Position, e ast.Expression, end token.Position) {
+	for _, c := range p.takeComments(start, e.Pos(), true) {
+		p.write(c.Literal)
+		p.afterComment(c)
+	}
+	p.expression(e, parser.LOWEST)
+	for _, c := range p.takeComments(start, end, true) {
+		p.write(" " + c.Literal)
+		if isLineComment(c) {
+			p.newline()
+		}
+	}
+}
+
+func (p *printer) afterComment(c token.Token) {
+	if isLineComment(c) {
+		p.newline()
+	} else {
+		p.write(" ")
+	}
+}
+
+func isLineComment(c token.Token) bool {
+	return strings.HasPrefix(c.Literal, "//")
+}
+
+//'}'で終わるif式の直後に'(' '[' '-'で始まる式文が続くと、呼び出しや添字、引き算として
+//前の式につながってしまうので、その場合だけif式の後にも';'を書く
+func continuesExpression(next ast.Statement) bool {
+	stmt, ok := next.(*ast.ExpressionStatement)
+	if !ok {
+		return false
+	}
+	switch stmt.Token.Type {
+	case token.LPAREN, token.LBRACKET, token.MINUS:
+		return true
+	}
+	return false
+}
+
+//'{'の前の空白か改行もここで書く。空のブロックは、どちらの書き方でも同じ行に{}と書く
+func (p *printer) block(b *ast.BlockStatement) {
+	if len(b.Statements) == 0 && (len(p.comments) == 0 || p.comments[0].Pos.Offset >= b.Rbrace.Pos.Offset) {
+		p.write(" {}")
+		return
+	}
+	if p.config.Braces == NextLine {
+		p.newline()
+		p.write("{")
+	} else {
+		p.write(" {")
+	}
+	p.indent++
+	p.statements(b.Statements, b.Rbrace.Pos, true)
+	p.indent--
+	p.newline()
+	p.write("}")
+	p.lastLine = b.Rbrace.Pos.Line
+}
+
+//リテラルや識別子など、括弧で囲む必要のない式の優先度
+const primary = parser.INDEX + 1
+
+var infixPrecedences = map[string]int{
+	"||": parser.LOGICAL_OR,
+	"&&": parser.LOGICAL_AND,
+	"==": parser.EQUALS,
+	"!=": parser.EQUALS,
+	"<":  parser.LESSGRATER,
+	">":  parser.LESSGRATER,
+	"<=": parser.LESSGRATER,
+	">=": parser.LESSGRATER,
+	"+":  parser.SUM,
+	"-":  parser.SUM,
+	"|":  parser.SUM,
+	"^":  parser.SUM,
+	"*":  parser.PRODUCT,
+	"/":  parser.PRODUCT,
+	"%":  parser.PRODUCT,
+	"&":  parser.PRODUCT,
+	"<<": parser.PRODUCT,
+	">>": parser.PRODUCT,
+}
+
+func precedence(e ast.Expression) int {
+	switch e := e.(type) {
+	case *ast.AssignExpression:
+		return parser.ASSIGN
+	case *ast.InfixExpression:
+		return infixPrecedences[e.Operator]
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression:
+		return parser.INDEX
+	default:
+		return primary
+	}
+}
+
+//minPrecedenceより弱く結合する式は、括弧で囲まないと元の構文木に戻らない
+func (p *printer) expression(e ast.Expression, minPrecedence int) {
+	if precedence(e) < minPrecedence {
+		p.write("(")
+		p.expression(e, parser.LOWEST)
+		p.write(")")
+		return
+	}
+
+	switch e := e.(type) {
+	case *ast.Identifier:
+		p.write(e.Value)
+	case *ast.Boolean:
+		p.write(e.Token.Literal)
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral:
+		//文字列のエスケープや数値の書き方(0x, _など)は書いたとおりに残す
+		p.write(p.source[e.Pos().Offset:e.End().Offset])
+	case *ast.PrefixExpression:
+		//--や!!というトークンはないので、前置演算子が続いても空白を入れずに書ける
+		p.write(e.Operator)
+		p.expression(e.Right, parser.PREFIX)
+	case *ast.InfixExpression:
+		//左結合なので、同じ優先度の式は右辺だけ括弧で囲む
+		prec := infixPrecedences[e.Operator]
+		p.expression(e.Left, prec)
+		p.write(" " + e.Operator + " ")
+		p.expression(e.Right, prec+1)
+	case *ast.AssignExpression:
+		//右結合なので、a = b = 1の右辺は括弧で囲まない
+		p.expression(e.Target, parser.CALL)
+		p.write(" " + e.Operator + " ")
+		p.expression(e.Value, parser.ASSIGN)
+	case *ast.CallExpression:
+		p.expression(e.Function, parser.CALL)
+		p.list("(", ")", e.Token.Pos, e.Rparen.Pos, expressionSpans(e.Arguments), func(i int) {
+			p.expression(e.Arguments[i], parser.LOWEST)
+		})
+	case *ast.IndexExpression:
+		//a[0][1]やf(x)[0]のように、呼び出しと添字は続けて書ける
+		p.expression(e.Left, parser.CALL)
+		p.write("[")
+		p.expression(e.Index, parser.LOWEST)
+		p.write("]")
+	case *ast.ArrayLiteral:
+		p.list("[", "]", e.Token.Pos, e.Rbracket.Pos, expressionSpans(e.Elements), func(i int) {
+			p.expression(e.Elements[i], parser.LOWEST)
+		})
+	case *ast.HashLiteral:
+		spans := []span{}
+		for _, pair := range e.Pairs {
+			spans = append(spans, span{pair.Key.Pos(), pair.Value.End()})
+		}
+		p.list("{", "}", e.Token.Pos, e.Rbrace.Pos, spans, func(i int) {
+			p.expression(e.Pairs[i].Key, parser.LOWEST)
+			p.write(": ")
+			p.expression(e.Pairs[i].Value, parser.LOWEST)
+		})
+	case *ast.IfExpression:
+		p.write("if (")
+		p.condition(e.Pos(), e.Condition, e.Consequence.Pos())
+		p.write(")")
+		p.block(e.Consequence)
+		if e.Alternative != nil {
+			if p.config.Braces == NextLine {
+				p.newline()
+				p.write("else")
+			} else {
+				p.write(" else")
+			}
+			p.block(e.Alternative)
+		}
+	case *ast.FunctionLiteral:
+		p.write(e.Token.Literal)
+		p.parameters(e.Token.Pos, e.Body.Pos(), e.Parameters)
+		p.block(e.Body)
+	case *ast.MacroLiteral:
+		p.write(e.Token.Literal)
+		p.parameters(e.Token.Pos, e.Body.Pos(), e.Parameters)
+		p.block(e.Body)
+	}
+}
+
+//引数リストの'('と')'の位置は残っていないので、fnと本体の'{'の間を引数リストとみなす
+func (p *printer) parameters(start, end token.Position, parameters []*ast.Identifier) {
+	spans := []span{}
+	for _, param := range parameters {
+		spans = append(spans, span{param.Pos(), param.End()})
+	}
+	p.list("(", ")", start, end, spans, func(i int) {
+		p.write(parameters[i].Value)
+	})
+}
+
+//listの要素のソース上の範囲
+type span struct {
+	start, end token.Position
+}
+
+func expressionSpans(expressions []ast.Expression) []span {
+	spans := []span{}
+	for _, e := range expressions {
+		spans = append(spans, span{e.Pos(), e.End()})
+	}
+	return spans
+}
+
+//まず1行に並べてみて、書いた行のどれかがWidthを超えたら、要素ごとに改行して書き直す。
+//最後の要素の後に','は付けない。
+//要素の間(startの開き括弧からendの閉じ括弧まで)のコメントは、同じ行にある前の要素に付けて、
+//その後ろに書く。行コメントや別の行にあるコメントがあれば要素ごとに改行して、
+//別の行にあるコメントはそれぞれ次の要素の前の行に書く
+func (p *printer) list(open, close string, start, end token.Position, spans []span, element func(i int)) {
+	n := len(spans)
+	if p.commentsFitInLine(start, end, spans) {
+		state := p.save()
+		p.write(open)
+		for j, c := range p.takeComments(start, gapEnd(0, spans, end), true) {
+			if j > 0 {
+				p.write(" ")
+			}
+			p.write(c.Literal)
+			if n > 0 {
+				p.write(" ")
+			}
+		}
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				p.write(", ")
+			}
+			element(i)
+			for _, c := range p.takeComments(spans[i].end, gapEnd(i+1, spans, end), true) {
+				p.write(" " + c.Literal)
+			}
+		}
+		p.write(close)
+		if n == 0 || p.fits(state) {
+			return
+		}
+		p.restore(state)
+	}
+
+	p.write(open)
+	p.indent++
+	p.sameLineComments(start, gapEnd(0, spans, end))
+	after := start
+	for i := 0; i < n; i++ {
+		p.listComments(after, spans[i].start)
+		p.newline()
+		element(i)
+		if i < n-1 {
+			p.write(",")
+		}
+		after = spans[i].end
+		p.sameLineComments(after, gapEnd(i+1, spans, end))
+	}
+	p.listComments(after, end)
+	p.indent--
+	p.newline()
+	p.write(close)
+}
+
+//i番目の要素の前の隙間の終わり。最後の要素の後ろは閉じ括弧まで
+func gapEnd(i int, spans []span, end token.Position) token.Position {
+	if i < len(spans) {
+		return spans[i].start
+	}
+	return end
+}
+
+//要素の間のコメントが全て、前の要素(最初は開き括弧)と同じ行にある1行のブロックコメントなら、
+//1行に並べたまま書ける。要素の中のコメントは、その要素を書くときに扱うので数えない
+func (p *printer) commentsFitInLine(start, end token.Position, spans []span) bool {
+	after := start
+	for i := 0; i <= len(spans); i++ {
+		for _, c := range p.takeComments(after, gapEnd(i, spans, end), false) {
+			if c.Pos.Line != after.Line || !strings.HasPrefix(c.Literal, "/*") || strings.Contains(c.Literal, "\n") {
+				return false
+			}
+		}
+		if i < len(spans) {
+			after = spans[i].end
+		}
+	}
+	return true
+}
+
+//afterと同じ行から始まるコメントを、afterの後ろに続けて書く
+func (p *printer) sameLineComments(after, before token.Position) {
+	for _, c := range p.takeComments(after, before, false) {
+		if c.Pos.Line != after.Line {
+			return
+		}
+		p.takeComments(after, c.End, true) //前のコメントは取り除いてあるので、cだけが取れる
+		p.write(" " + c.Literal)
+	}
+}
+
+//afterとbeforeの間にあるコメントを、1つずつ別の行に書く
+func (p *printer) listComments(after, before token.Position) {
+	for _, c := range p.takeComments(after, before, true) {
+		p.newline()
+		p.write(c.Literal)
+	}
+}
+
+//afterより後でbeforeより前にあるコメントを返す。removeがtrueなら、まだ書いていないコメントから取り除く。
+//p.commentsはsaveした状態と配列を共有しているので、書き換えずに新しく作る
+func (p *printer) takeComments(after, before token.Position, remove bool) []token.Token {
+	taken := []token.Token{}
+	rest := []token.Token{}
+	for _, c := range p.comments {
+		if c.Pos.Offset > after.Offset && c.Pos.Offset < before.Offset {
+			taken = append(taken, c)
+		} else {
+			rest = append(rest, c)
+		}
+	}
+	if remove {
+		p.comments = rest
+	}
+	return taken
+}
+
+//stateの後に書いた部分を含む行が、全てWidthに収まっているか
+func (p *printer) fits(state printerState) bool {
+	out := p.buf.Bytes()
+	start := bytes.LastIndexByte(out[:state.length], '\n') + 1
+	for _, line := range strings.Split(string(out[start:]), "\n") {
+		if utf8.RuneCountInString(line) > p.config.Width {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,213 @@
+package format
+
+import (
+	"interpreter-go/lexer"
+	"interpreter-go/parser"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let   x=1", "let x = 1;\n"},
+		{"puts(1)\nputs(2);", "puts(1);\nputs(2);\n"},
+		{"", ""},
+		//括弧は優先順位に必要なものだけを残す
+		{"(1 + 2) * 3", "(1 + 2) * 3;\n"},
+		{"1 + (2 * 3)", "1 + 2 * 3;\n"},
+		{"(1 - 2) - 3", "1 - 2 - 3;\n"},
+		{"1 - (2 - 3)", "1 - (2 - 3);\n"},
+		{"(a << 1) + (b & c)", "a << 1 + b & c;\n"},
+		{"(a | b) & c", "(a | b) & c;\n"},
+		{"-(a + b)", "-(a + b);\n"},
+		{"-(-a)", "--a;\n"},
+		{"-(-5)", "--5;\n"},
+		{"!(!x)", "!!x;\n"},
+		{"!!true", "!!true;\n"},
+		{"-(!x)", "-!x;\n"},
+		{"~(~x)", "~~x;\n"},
+		{"(-a)[0]", "(-a)[0];\n"},
+		{"-(a[0])", "-a[0];\n"},
+		{"f(1)[2](3)", "f(1)[2](3);\n"},
+		{"(fn(x) { x })(1)", "fn(x) {\n    x;\n}(1);\n"},
+		{"a = (b = 1)", "a = b = 1;\n"},
+		{"(a = 1) + 2", "(a = 1) + 2;\n"},
+		{"x[0] += a || b", "x[0] += a || b;\n"},
+		{"!(a && b) || c", "!(a && b) || c;\n"},
+		//リテラルは書いたとおりに残す
+		{`let s = "a\tb\n\u{3042}"`, "let s = \"a\\tb\\n\\u{3042}\";\n"},
+		{"let n = 0xFF + 1_000 + 1.5e3", "let n = 0xFF + 1_000 + 1.5e3;\n"},
+		{`{"a":1,true:[1,2]}`, "{\"a\": 1, true: [1, 2]};\n"},
+		{"[]; {}; f()", "[];\n{};\nf();\n"},
+		//ブロック
+		{"if(x){1}else{2}", "if (x) {\n    1;\n} else {\n    2;\n}\n"},
+		{"let f=fn(a,b){return a+b;}", "let f = fn(a, b) {\n    return a + b;\n};\n"},
+		{"let f = fn() {}", "let f = fn() {};\n"},
+		{"let m = macro(x) { quote(unquote(x)) }", "let m = macro(x) {\n    quote(unquote(x));\n};\n"},
+		{"while (i < 3) { i += 1; if (i == 2) { break; } continue; }",
+			"while (i < 3) {\n    i += 1;\n    if (i == 2) {\n        break;\n    }\n    continue;\n}\n"},
+		{"for (x in [1, 2]) { puts(x) }", "for (x in [1, 2]) {\n    puts(x);\n}\n"},
+		{"map(a, fn(x) { x * 2 })", "map(a, fn(x) {\n    x * 2;\n});\n"},
+		//if式の後に'('などで始まる式文が続くときは';'で区切る
+		{"if (x) { 1 }; (y)", "if (x) {\n    1;\n};\ny;\n"},
+		{"if (x) { 1 }; [y]", "if (x) {\n    1;\n};\n[y];\n"},
+		{"if (x) { 1 }; -y", "if (x) {\n    1;\n};\n-y;\n"},
+		{"if (x) { 1 } y", "if (x) {\n    1;\n}\ny;\n"},
+		//空行は1行まで残す
+		{"let a = 1;\n\n\n\nlet b = 2;\nlet c = 3;", "let a = 1;\n\nlet b = 2;\nlet c = 3;\n"},
+		{"if (x) {\n\n  1\n\n  2\n\n}", "if (x) {\n    1;\n\n    2;\n}\n"},
+		//コメント
+		{"// header\nlet a = 1; // one\n\n/* block\n   comment */\nlet b = 2;",
+			"// header\nlet a = 1; // one\n\n/* block\n   comment */\nlet b = 2;\n"},
+		{"let f = fn(x) { x // value\n} // f", "let f = fn(x) {\n    x; // value\n}; // f\n"},
+		{"let f = fn() {\n  // nothing yet\n}", "let f = fn() {\n    // nothing yet\n};\n"},
+		//要素の間のコメントは、同じ行にある前の要素の後ろに書く。
+		//行コメントや別の行にあるコメントがあれば、要素ごとに改行する
+		{"let a = [1, /* one */ 2];\nlet b = 3;", "let a = [1 /* one */, 2];\nlet b = 3;\n"},
+		{"f(a /* p */, b)", "f(a /* p */, b);\n"},
+		{"let f = fn(a /* p */, b) { a }", "let f = fn(a /* p */, b) {\n    a;\n};\n"},
+		{"f(a, // first\n  b)", "f(\n    a, // first\n    b\n);\n"},
+		{"f(\n  // lead\n  a,\n  b)", "f(\n    // lead\n    a,\n    b\n);\n"},
+		{"let h = {\"a\": 1 // last\n}", "let h = {\n    \"a\": 1 // last\n};\n"},
+		{"let f = fn(/* none */) { 1 }", "let f = fn(/* none */) {\n    1;\n};\n"},
+		{"f(a, [1, /* in */ 2])", "f(a, [1 /* in */, 2]);\n"},
+		//if、while、forの括弧の中のコメントは、本体に入れずに括弧の中に残す
+		{"if (x /* c */) { 1 }", "if (x /* c */) {\n    1;\n}\n"},
+		{"if (/* c */ x) { 1 }", "if (/* c */ x) {\n    1;\n}\n"},
+		{"while (x // c\n) { 1 }", "while (x // c\n) {\n    1;\n}\n"},
+		{"for (i in xs /* c */) { 1 }", "for (i in xs /* c */) {\n    1;\n}\n"},
+		{"let a /* note */ = [1, 2];", "let a = [1, 2]; /* note */\n"},
+		{"let a = 1;\n// last", "let a = 1;\n// last\n"},
+		{"// only", "// only\n"},
+		{"#!/usr/bin/env monkey\n\nputs(1)", "#!/usr/bin/env monkey\n\nputs(1);\n"},
+		{"#!/usr/bin/env monkey\nputs(1)", "#!/usr/bin/env monkey\nputs(1);\n"},
+	}
+
+	for _, tt := range tests {
+		got, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.input, err)
+			continue
+		}
+		if string(got) != tt.expected {
+			t.Errorf("%q: output wrong.\nexpected=%q\ngot=     %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestSourceWidth(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let xs = [1, 2, 3];", "let xs = [1, 2, 3];\n"},
+		{"let xs = [first, second, third];", "let xs = [\n  first,\n  second,\n  third\n];\n"},
+		//内側の要素だけを折り返して収まれば、外側は1行のままにする
+		{"f(alpha, {\"key\": beta})", "f(alpha, {\n  \"key\": beta\n});\n"},
+		{"f(alphabet, [1, 2], gamma)", "f(\n  alphabet,\n  [1, 2],\n  gamma\n);\n"},
+		{"let g = fn(alpha, beta, gamma) { 1 }", "let g = fn(\n  alpha,\n  beta,\n  gamma\n) {\n  1;\n};\n"},
+		//1行に収まらない識別子は折り返せない
+		{"let averyveryverylongname = 1;", "let averyveryverylongname = 1;\n"},
+	}
+
+	config := Config{Width: 20, Indent: 2}
+	for _, tt := range tests {
+		got, err := config.Source([]byte(tt.input))
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.input, err)
+			continue
+		}
+		if string(got) != tt.expected {
+			t.Errorf("%q: output wrong.\nexpected=%q\ngot=     %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestSourceNextLineBraces(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"if(x){1}else{2}", "if (x)\n{\n  1;\n}\nelse\n{\n  2;\n}\n"},
+		{"let f=fn(a){a}", "let f = fn(a)\n{\n  a;\n};\n"},
+		{"while (x) { for (y in z) { y } }", "while (x)\n{\n  for (y in z)\n  {\n    y;\n  }\n}\n"},
+		{"let f = fn() {}", "let f = fn() {};\n"},
+	}
+
+	config := Config{Width: 80, Indent: 2, Braces: NextLine}
+	for _, tt := range tests {
+		got, err := config.Source([]byte(tt.input))
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.input, err)
+			continue
+		}
+		if string(got) != tt.expected {
+			t.Errorf("%q: output wrong.\nexpected=%q\ngot=     %q", tt.input, tt.expected, got)
+		}
+		again, err := config.Source(got)
+		if err != nil || string(again) != string(got) {
+			t.Errorf("%q: formatting is not idempotent. second=%q (%v)", tt.input, again, err)
+		}
+	}
+}
+
+//整形しても構文木は変わらず、整形済みのソースを整形しても変わらない
+func TestSourcePreservesProgram(t *testing.T) {
+	inputs := []string{
+		"let fib = fn(n) { if (n < 2) { return n; } else { fib(n - 1) + fib(n - 2) } }; fib(10)",
+		"let x = (1 + 2) * (3 - (4 - 5)) / -(-6) % 7 << 1 >> 2 & 3 | 4 ^ ~5",
+		"a = b += c[0] = d || e && !f == g != (h < i) <= j > k >= l",
+		"let h = {\"one\": fn(x) { x }(1), [1][0]: if (a) { b } else { c } + 1}",
+		"let r = reduce([aaaaaaaaaaaa, bbbbbbbbbbbbbbb, cccccccccccccccc, dddddddddddddddd], 0, fn(acc, x) { acc + x })",
+		"if (x) { 1 }\n(y)\nif (x) { 2 }; [z]",
+		"while (true) { for (k in keys({\"a\": 1})) { if (k == \"a\") { continue; } break; } }",
+		"let unless = macro(c, a, b) { quote(if (!(unquote(c))) { unquote(a) } else { unquote(b) }) };",
+		"// a\nlet a = 1; /* b */ let b = [1, // c\n 2];\n/* d */",
+		"-(-5) + !(!x) - ~(~y)",
+		"f(a, // first\n b, /* x */ c)\nlet h = {\"a\": 1, // one\n \"b\": [2, /* two */ 3] /* end */}",
+		"f(a /* p */, b)\nlet g = fn(a /* p */, b) { if (a /* c */ && b // d\n) { a } }",
+		"while (/* w */ x) { for (i in [1, /* one */ 2] // xs\n) { i } }",
+	}
+
+	for _, input := range inputs {
+		formatted, err := Source([]byte(input))
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", input, err)
+			continue
+		}
+		if expected, got := parse(t, input), parse(t, string(formatted)); got != expected {
+			t.Errorf("%q: program changed.\nexpected=%q\ngot=     %q\nformatted:\n%s", input, expected, got, formatted)
+		}
+
+		again, err := Source(formatted)
+		if err != nil {
+			t.Errorf("%q: formatted source has errors: %v\n%s", input, err, formatted)
+			continue
+		}
+		if string(again) != string(formatted) {
+			t.Errorf("%q: formatting is not idempotent.\nfirst=%q\nsecond=%q", input, formatted, again)
+		}
+	}
+}
+
+func parse(t *testing.T, input string) string {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("%q: parse errors %v", input, p.Errors())
+	}
+	return program.String()
+}
+
+func TestSourceSyntaxError(t *testing.T) {
+	_, err := Source([]byte("let x = 1;\nlet = 2;"))
+	syntaxErr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("expected *SyntaxError, got %T (%v)", err, err)
+	}
+	if len(syntaxErr.Errors) != 1 || syntaxErr.Error() != "2:5: expected IDENT" {
+		t.Errorf("error wrong. got=%q", syntaxErr.Error())
+	}
+}
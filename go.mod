@@ -1,3 +1,5 @@
 module interpreter-go
 
 go 1.13
+
+require golang.org/x/term v0.0.0-20210503060354-a79de5458b56
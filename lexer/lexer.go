@@ -1,26 +1,103 @@
 package lexer
 
 import (
+	"bytes"
+	"fmt"
 	"interpreter-go/token"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Lexer struct {
 	input        string
 	position     int
 	readPosition int
-	ch           byte
+	ch           rune //不正なUTF-8のバイトはutf8.RuneErrorになる
+	line         int  //chの行
+	column       int  //chの列。バイトではなく文字単位で数える
+	keepComments bool //コメントを読み飛ばさずにCOMMENTトークンとして返す
 }
 
 func New(input string) Lexer {
 	lexer := Lexer{
 		input: input,
+		line:  1,
 	}
 	lexer.readChar()
 	return lexer
 }
 
+//スクリプトの先頭の"#!"の行を読み飛ばす。行番号がずれないように改行は残す
+func StripShebang(source string) string {
+	if !strings.HasPrefix(source, "#!") {
+		return source
+	}
+	if i := strings.IndexByte(source, '\n'); i >= 0 {
+		return source[i:]
+	}
+	return ""
+}
+
+//フォーマッターなど、コメントを残したいツール用
+func NewWithComments(input string) Lexer {
+	lexer := New(input)
+	lexer.keepComments = true
+	return lexer
+}
+
 func (l *Lexer) NextToken() token.Token {
-	l.skipWhiteSpace()
+	for {
+		l.skipWhiteSpace()
+		pos := l.currentPosition()
+		tok, isComment := l.readComment()
+		if !isComment {
+			tok = l.readToken()
+		} else if !l.keepComments && tok.Type == token.COMMENT {
+			continue
+		}
+		tok.Pos = pos
+		tok.End = l.currentPosition()
+		return tok
+	}
+}
+
+//"//"は行末まで、"/* */"は入れ子を数えて対応する"*/"までをコメントとして読む。
+//閉じられていない"/*"は、開始位置のILEEGALにする
+func (l *Lexer) readComment() (token.Token, bool) {
+	if l.ch != '/' || (l.peekChar() != '/' && l.peekChar() != '*') {
+		return token.Token{}, false
+	}
+	start := l.position
+
+	if l.peekChar() == '/' {
+		for l.ch != '\n' && l.ch != 0 {
+			l.readChar()
+		}
+		return token.Token{Type: token.COMMENT, Literal: l.input[start:l.position]}, true
+	}
+
+	depth := 0
+	for {
+		switch {
+		case l.ch == 0:
+			return token.Token{Type: token.ILEEGAL, Literal: l.input[start:l.position], Message: "unterminated block comment"}, true
+		case l.ch == '/' && l.peekChar() == '*':
+			depth++
+			l.readChar()
+		case l.ch == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+		}
+		l.readChar()
+		if depth == 0 {
+			return token.Token{Type: token.COMMENT, Literal: l.input[start:l.position]}, true
+		}
+	}
+}
+
+func (l *Lexer) readToken() token.Token {
 	var tok token.Token
 	switch l.ch {
 	case '=':
@@ -33,7 +110,7 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		tok = l.readOperator(token.PLUS, '=', token.PLUS_ASSIGN)
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -42,20 +119,50 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
 		tok = newToken(token.RBRACE, l.ch)
+	case '[':
+		tok = newToken(token.LBRACKET, l.ch)
+	case ']':
+		tok = newToken(token.RBRACKET, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		tok = l.readOperator(token.MINUS, '=', token.MINUS_ASSIGN)
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		tok = l.readOperator(token.SLASH, '=', token.SLASH_ASSIGN)
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		tok = l.readOperator(token.ASTERISK, '=', token.ASTERISK_ASSIGN)
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tok = l.readTwoCharToken(token.LT_EQ)
+		case '<':
+			tok = l.readTwoCharToken(token.SHIFT_LEFT)
+		default:
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.RT, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tok = l.readTwoCharToken(token.RT_EQ)
+		case '>':
+			tok = l.readTwoCharToken(token.SHIFT_RIGHT)
+		default:
+			tok = newToken(token.RT, l.ch)
+		}
+	case '&':
+		tok = l.readOperator(token.BIT_AND, '&', token.AND)
+	case '|':
+		tok = l.readOperator(token.BIT_OR, '|', token.OR)
+	case '^':
+		tok = newToken(token.BIT_XOR, l.ch)
+	case '~':
+		tok = newToken(token.BIT_NOT, l.ch)
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -65,6 +172,13 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = newToken(token.BANG, l.ch)
 		}
+	case '"':
+		literal, message := l.readString()
+		if message == "" {
+			tok = token.Token{Type: token.STRING, Literal: literal}
+		} else {
+			tok = token.Token{Type: token.ILEEGAL, Literal: literal, Message: message}
+		}
 	case 0:
 		tok = newToken(token.EOF, l.ch)
 	default:
@@ -73,9 +187,7 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookUpIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
-			return tok
+			return l.readNumber()
 		} else {
 			tok = newToken(token.ILEEGAL, l.ch)
 		}
@@ -84,19 +196,50 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
+//次の文字がnextなら2文字のトークンdouble、そうでなければ1文字のトークンsingleを返す
+func (l *Lexer) readOperator(single token.TokenType, next rune, double token.TokenType) token.Token {
+	if l.readPosition < len(l.input) && l.peekChar() == next {
+		return l.readTwoCharToken(double)
+	}
+	return newToken(single, l.ch)
+}
+
+//l.chと次の文字で1つのトークンにする
+func (l *Lexer) readTwoCharToken(tokenType token.TokenType) token.Token {
+	ch := l.ch
+	l.readChar()
+	return token.Token{Type: tokenType, Literal: string(ch) + string(l.ch)}
+}
+
 func (l *Lexer) readChar() {
+	//EOFより先には進めない
+	if l.readPosition > len(l.input) {
+		return
+	}
+	if l.ch == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+	width := 1
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 	l.position = l.readPosition
-	l.readPosition = l.readPosition + 1
+	l.readPosition = l.readPosition + width
+}
+
+func (l Lexer) currentPosition() token.Position {
+	return token.Position{Line: l.line, Column: l.column, Offset: l.position}
 }
 
+//Goと同じく、2文字目からは数字も使える
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || unicode.IsDigit(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
@@ -108,30 +251,161 @@ func (l *Lexer) skipWhiteSpace() {
 	}
 }
 
-func (l *Lexer) readNumber() string {
+//小数点か指数があればFLOAT、なければINT。
+//"1."や"1.5e"のように小数点や指数の後ろに数字が続かない場合は、続く英数字までを
+//1つのILEEGALにする。
+//0x、0o、0bで始まる整数は、続く英数字と'_'をまとめて1つのリテラルにする。
+//"0x"や"1__0"のような不正なリテラルもそのまま返し、パーサーがエラーにする
+func (l *Lexer) readNumber() token.Token {
 	position := l.position
-	for isDigit(l.ch) {
+	if l.ch == '0' && strings.ContainsRune("xXoObB", l.peekChar()) {
+		l.readChar()
+		l.readChar()
+		for isLetter(l.ch) || isDigit(l.ch) {
+			l.readChar()
+		}
+		return token.Token{Type: token.INT, Literal: l.input[position:l.position]}
+	}
+
+	tokenType := token.TokenType(token.INT)
+	malformed := false
+	l.readDigits()
+	if l.ch == '.' {
+		tokenType = token.FLOAT
+		l.readChar()
+		malformed = !isDigit(l.ch)
+		l.readDigits()
+	}
+	if l.ch == 'e' || l.ch == 'E' {
+		tokenType = token.FLOAT
+		l.readChar()
+		if l.ch == '+' || l.ch == '-' {
+			l.readChar()
+		}
+		malformed = malformed || !isDigit(l.ch)
+		l.readDigits()
+	}
+
+	if malformed {
+		for isLetter(l.ch) || isDigit(l.ch) {
+			l.readChar()
+		}
+		literal := l.input[position:l.position]
+		return token.Token{Type: token.ILEEGAL, Literal: literal, Message: fmt.Sprintf("malformed float literal %q", literal)}
+	}
+	return token.Token{Type: tokenType, Literal: l.input[position:l.position]}
+}
+
+//'_'は桁の区切りとして数字と一緒に読む
+func (l *Lexer) readDigits() {
+	for isDigit(l.ch) || l.ch == '_' {
+		l.readChar()
+	}
+}
+
+//エスケープシーケンスを展開した文字列を返す。
+//閉じられていない文字列や不正なエスケープの場合は、元の文字列とエラーの理由を返す
+func (l *Lexer) readString() (string, string) {
+	start := l.position
+	var out bytes.Buffer
+	for {
+		l.readChar()
+		switch l.ch {
+		case '"':
+			return out.String(), ""
+		case 0:
+			return l.input[start:l.position], "unterminated string literal"
+		case '\\':
+			escape := l.position
+			l.readChar()
+			switch l.ch {
+			case 'n':
+				out.WriteByte('\n')
+			case 't':
+				out.WriteByte('\t')
+			case '"':
+				out.WriteByte('"')
+			case '\\':
+				out.WriteByte('\\')
+			case 'u':
+				r, ok := l.readUnicodeEscape()
+				if !ok {
+					return l.invalidEscape(start, escape)
+				}
+				out.WriteRune(r)
+			case 0:
+				return l.input[start:l.position], "unterminated string literal"
+			default:
+				return l.invalidEscape(start, escape)
+			}
+		default:
+			out.WriteRune(l.ch)
+		}
+	}
+}
+
+//不正なエスケープの後の残りを読み飛ばす。文字列が閉じられていなければ、そちらをエラーにする
+func (l *Lexer) invalidEscape(start, escape int) (string, string) {
+	sequence := l.input[escape:l.readPosition]
+	l.skipString()
+	if l.ch == 0 {
+		return l.input[start:l.position], "unterminated string literal"
+	}
+	return l.input[start:l.position], fmt.Sprintf("invalid escape sequence %s", sequence)
+}
+
+//\u{XXXX}の形式。l.chが'u'の状態で呼び出す
+func (l *Lexer) readUnicodeEscape() (rune, bool) {
+	if l.peekChar() != '{' {
+		return 0, false
+	}
+	l.readChar()
+	start := l.readPosition
+	for l.peekChar() != '}' {
+		if l.readPosition >= len(l.input) || l.peekChar() == '"' {
+			return 0, false
+		}
+		l.readChar()
+	}
+	hex := l.input[start:l.readPosition]
+	l.readChar()
+
+	code, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		return 0, false
+	}
+	return rune(code), true
+}
+
+//エラー時に、残りの文字列を読み飛ばして閉じ'"'の位置まで進める
+func (l *Lexer) skipString() {
+	for l.ch != '"' && l.ch != 0 {
+		if l.ch == '\\' {
+			l.readChar()
+		}
 		l.readChar()
 	}
-	return l.input[position:l.position]
 }
 
-func (l Lexer) peekChar() byte {
+func (l Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return '0'
 	} else {
-		return l.input[l.readPosition]
+		r, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+		return r
 	}
 }
 
-func isDigit(ch byte) bool {
+//数値リテラルはASCIIの数字だけで書く
+func isDigit(ch rune) bool {
 	return ch >= '0' && ch <= '9'
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && 'z' >= ch || 'A' <= ch && 'Z' >= ch || '_' == ch
+//Goの識別子と同じく、Unicodeの文字と'_'
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || '_' == ch
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
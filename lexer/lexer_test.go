@@ -14,7 +14,7 @@ let add = fn(x, y) {
 }
 
 let result = add(five,ten);
-!-/*5;	
+!-/ *5;	
 5 < 10 >5;
 
 if (5 < 10) {
@@ -306,4 +306,375 @@ if (5 < 10) {
 			t.Fatalf("tests[%d] - literal wrong expected=%q got=%q",i,tt.expectedLiteral,token.Literal)
 		}
 	 }
-}
\ No newline at end of file
+}
+func TestStringToken(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{`"foobar"`, token.STRING, "foobar"},
+		{`"foo bar"`, token.STRING, "foo bar"},
+		{`""`, token.STRING, ""},
+		{`"a\nb\tc"`, token.STRING, "a\nb\tc"},
+		{`"say \"hi\""`, token.STRING, `say "hi"`},
+		{`"back\\slash"`, token.STRING, `back\slash`},
+		{`"\u{41}\u{3042}"`, token.STRING, "Aあ"},
+		{`"日本語の文字列"`, token.STRING, "日本語の文字列"},
+		{`"unterminated`, token.ILEEGAL, `"unterminated`},
+		{`"bad\q"`, token.ILEEGAL, `"bad\q`},
+		{`"\u{zz}"`, token.ILEEGAL, `"\u{zz}`},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong expected=%q got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong expected=%q got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Fatalf("tests[%d] - expected EOF after string got=%q", i, next.Type)
+		}
+	}
+}
+
+func TestBracketToken(t *testing.T) {
+	input := `[1, 2][0];`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.LBRACKET, "["},
+		{token.INT, "0"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, string(rune(0))},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong expected=%q got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong expected=%q got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestColonToken(t *testing.T) {
+	l := New(`{"foo": "bar"}`)
+
+	expected := []token.TokenType{token.LBRACE, token.STRING, token.COLON, token.STRING, token.RBRACE, token.EOF}
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt {
+			t.Fatalf("tests[%d] - tokentype wrong expected=%q got=%q", i, tt, tok.Type)
+		}
+	}
+}
+
+func TestNumberToken(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{"5", token.INT, "5"},
+		{"3.14", token.FLOAT, "3.14"},
+		{"1e-9", token.FLOAT, "1e-9"},
+		{"1E+10", token.FLOAT, "1E+10"},
+		{"2.5e3", token.FLOAT, "2.5e3"},
+		{"1.", token.ILEEGAL, "1."},
+		{"1e", token.ILEEGAL, "1e"},
+		{"1ex", token.ILEEGAL, "1ex"},
+		{"1.5e", token.ILEEGAL, "1.5e"},
+		{"1.e5", token.ILEEGAL, "1.e5"},
+		{"2e+", token.ILEEGAL, "2e+"},
+		{"0xFF", token.INT, "0xFF"},
+		{"0o17", token.INT, "0o17"},
+		{"0B1010", token.INT, "0B1010"},
+		{"1_000_000", token.INT, "1_000_000"},
+		{"1_000.5_0", token.FLOAT, "1_000.5_0"},
+		{"0x", token.INT, "0x"},
+		{"0xFG", token.INT, "0xFG"},
+		{"1__0", token.INT, "1__0"},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong expected=%q got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong expected=%q got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestAssignOperators(t *testing.T) {
+	l := New(`x += 1 -= 2 *= 3 /= 4 = 5`)
+
+	expected := []token.TokenType{
+		token.IDENT, token.PLUS_ASSIGN, token.INT, token.MINUS_ASSIGN, token.INT,
+		token.ASTERISK_ASSIGN, token.INT, token.SLASH_ASSIGN, token.INT, token.ASSIGN, token.INT, token.EOF,
+	}
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt {
+			t.Fatalf("tests[%d] - tokentype wrong expected=%q got=%q", i, tt, tok.Type)
+		}
+	}
+}
+
+func TestComparisonAndBitwiseOperators(t *testing.T) {
+	l := New(`a <= b >= c && d || e % f & g | h ^ ~i << j >> k < l > m`)
+
+	expected := []token.TokenType{
+		token.IDENT, token.LT_EQ, token.IDENT, token.RT_EQ, token.IDENT, token.AND, token.IDENT,
+		token.OR, token.IDENT, token.PERCENT, token.IDENT, token.BIT_AND, token.IDENT, token.BIT_OR,
+		token.IDENT, token.BIT_XOR, token.BIT_NOT, token.IDENT, token.SHIFT_LEFT, token.IDENT,
+		token.SHIFT_RIGHT, token.IDENT, token.LT, token.IDENT, token.RT, token.IDENT, token.EOF,
+	}
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt {
+			t.Fatalf("tests[%d] - tokentype wrong expected=%q got=%q", i, tt, tok.Type)
+		}
+	}
+}
+
+func TestLoopKeywords(t *testing.T) {
+	l := New(`while for x in break continue`)
+
+	expected := []token.TokenType{token.WHILE, token.FOR, token.IDENT, token.IN, token.BREAK, token.CONTINUE, token.EOF}
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt {
+			t.Fatalf("tests[%d] - tokentype wrong expected=%q got=%q", i, tt, tok.Type)
+		}
+	}
+}
+
+func TestMacroToken(t *testing.T) {
+	l := New(`macro(x) { x }`)
+
+	expected := []token.TokenType{token.MACRO, token.LPAREN, token.IDENT, token.RPAREN, token.LBRACE, token.IDENT, token.RBRACE, token.EOF}
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt {
+			t.Fatalf("tests[%d] - tokentype wrong expected=%q got=%q", i, tt, tok.Type)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 10;\n  \"ab\" +\n\ty"
+
+	tests := []struct {
+		expectedType token.TokenType
+		expectedPos  token.Position
+		expectedEnd  token.Position
+	}{
+		{token.LET, token.Position{Line: 1, Column: 1, Offset: 0}, token.Position{Line: 1, Column: 4, Offset: 3}},
+		{token.IDENT, token.Position{Line: 1, Column: 5, Offset: 4}, token.Position{Line: 1, Column: 6, Offset: 5}},
+		{token.ASSIGN, token.Position{Line: 1, Column: 7, Offset: 6}, token.Position{Line: 1, Column: 8, Offset: 7}},
+		{token.INT, token.Position{Line: 1, Column: 9, Offset: 8}, token.Position{Line: 1, Column: 11, Offset: 10}},
+		{token.SEMICOLON, token.Position{Line: 1, Column: 11, Offset: 10}, token.Position{Line: 1, Column: 12, Offset: 11}},
+		{token.STRING, token.Position{Line: 2, Column: 3, Offset: 14}, token.Position{Line: 2, Column: 7, Offset: 18}},
+		{token.PLUS, token.Position{Line: 2, Column: 8, Offset: 19}, token.Position{Line: 2, Column: 9, Offset: 20}},
+		{token.IDENT, token.Position{Line: 3, Column: 2, Offset: 22}, token.Position{Line: 3, Column: 3, Offset: 23}},
+		{token.EOF, token.Position{Line: 3, Column: 3, Offset: 23}, token.Position{Line: 3, Column: 3, Offset: 23}},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong expected=%q got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Pos != tt.expectedPos {
+			t.Errorf("tests[%d] - pos wrong expected=%+v got=%+v", i, tt.expectedPos, tok.Pos)
+		}
+		if tok.End != tt.expectedEnd {
+			t.Errorf("tests[%d] - end wrong expected=%+v got=%+v", i, tt.expectedEnd, tok.End)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `let x = 1; // line comment
+/* block /* nested */ still comment */ x / 2 /**/
+// last line`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		skipped         bool
+	}{
+		{token.LET, "let", false},
+		{token.IDENT, "x", false},
+		{token.ASSIGN, "=", false},
+		{token.INT, "1", false},
+		{token.SEMICOLON, ";", false},
+		{token.COMMENT, "// line comment", true},
+		{token.COMMENT, "/* block /* nested */ still comment */", true},
+		{token.IDENT, "x", false},
+		{token.SLASH, "/", false},
+		{token.INT, "2", false},
+		{token.COMMENT, "/**/", true},
+		{token.COMMENT, "// last line", true},
+		{token.EOF, "\x00", false},
+	}
+
+	skipping := New(input)
+	keeping := NewWithComments(input)
+	for i, tt := range tests {
+		tok := keeping.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - with comments expected=%q %q got=%q %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+		if tt.skipped {
+			continue
+		}
+		tok = skipping.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - expected=%q %q got=%q %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestCommentPositions(t *testing.T) {
+	l := NewWithComments("x /* a\nb */ y")
+
+	expected := []struct {
+		expectedType token.TokenType
+		expectedPos  token.Position
+		expectedEnd  token.Position
+	}{
+		{token.IDENT, token.Position{Line: 1, Column: 1, Offset: 0}, token.Position{Line: 1, Column: 2, Offset: 1}},
+		{token.COMMENT, token.Position{Line: 1, Column: 3, Offset: 2}, token.Position{Line: 2, Column: 5, Offset: 11}},
+		{token.IDENT, token.Position{Line: 2, Column: 6, Offset: 12}, token.Position{Line: 2, Column: 7, Offset: 13}},
+	}
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Pos != tt.expectedPos || tok.End != tt.expectedEnd {
+			t.Errorf("tests[%d] - expected=%q %+v-%+v got=%q %+v-%+v",
+				i, tt.expectedType, tt.expectedPos, tt.expectedEnd, tok.Type, tok.Pos, tok.End)
+		}
+	}
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+	for _, mode := range []func(string) Lexer{New, NewWithComments} {
+		l := mode("1 /* a /* b */")
+		l.NextToken()
+		tok := l.NextToken()
+		if tok.Type != token.ILEEGAL || tok.Literal != "/* a /* b */" {
+			t.Fatalf("expected ILEEGAL %q got=%q %q", "/* a /* b */", tok.Type, tok.Literal)
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Fatalf("expected EOF after comment got=%q", next.Type)
+		}
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{"名前", token.IDENT, "名前"},
+		{"café", token.IDENT, "café"},
+		{"_x1", token.IDENT, "_x1"},
+		{"変数２", token.IDENT, "変数２"},
+		{"π", token.IDENT, "π"},
+		{"１", token.ILEEGAL, "１"},
+		{"→", token.ILEEGAL, "→"},
+		{"\xff", token.ILEEGAL, "�"},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong expected=%q got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong expected=%q got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Fatalf("tests[%d] - expected EOF got=%q", i, next.Type)
+		}
+	}
+}
+
+//列は文字単位、Offsetはバイト単位で数える
+func TestUnicodePositions(t *testing.T) {
+	l := New("let 名前 = \"値\";\nあ + b")
+
+	tests := []struct {
+		expectedType token.TokenType
+		expectedPos  token.Position
+		expectedEnd  token.Position
+	}{
+		{token.LET, token.Position{Line: 1, Column: 1, Offset: 0}, token.Position{Line: 1, Column: 4, Offset: 3}},
+		{token.IDENT, token.Position{Line: 1, Column: 5, Offset: 4}, token.Position{Line: 1, Column: 7, Offset: 10}},
+		{token.ASSIGN, token.Position{Line: 1, Column: 8, Offset: 11}, token.Position{Line: 1, Column: 9, Offset: 12}},
+		{token.STRING, token.Position{Line: 1, Column: 10, Offset: 13}, token.Position{Line: 1, Column: 13, Offset: 18}},
+		{token.SEMICOLON, token.Position{Line: 1, Column: 13, Offset: 18}, token.Position{Line: 1, Column: 14, Offset: 19}},
+		{token.IDENT, token.Position{Line: 2, Column: 1, Offset: 20}, token.Position{Line: 2, Column: 2, Offset: 23}},
+		{token.PLUS, token.Position{Line: 2, Column: 3, Offset: 24}, token.Position{Line: 2, Column: 4, Offset: 25}},
+	}
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong expected=%q got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Pos != tt.expectedPos {
+			t.Errorf("tests[%d] - pos wrong expected=%+v got=%+v", i, tt.expectedPos, tok.Pos)
+		}
+		if tok.End != tt.expectedEnd {
+			t.Errorf("tests[%d] - end wrong expected=%+v got=%+v", i, tt.expectedEnd, tok.End)
+		}
+	}
+}
+
+func TestStripShebang(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"#!/usr/bin/env monkey\n1", "\n1"},
+		{"#!monkey", ""},
+		{"1\n#!x", "1\n#!x"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := StripShebang(tt.input); got != tt.expected {
+			t.Errorf("StripShebang(%q) wrong. expected=%q got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
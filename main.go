@@ -1,18 +1,317 @@
 package main
 
 import (
+	"bytes"
+	"flag"
 	"fmt"
+	"interpreter-go/ast"
+	"interpreter-go/compiler"
+	"interpreter-go/evaluator"
+	"interpreter-go/format"
+	"interpreter-go/lexer"
+	"interpreter-go/object"
+	"interpreter-go/parser"
 	"interpreter-go/repl"
+	"interpreter-go/vm"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/user"
 )
 
-func main(){
-	user,err := user.Current()
+//終了コード
+const (
+	exitOK    = 0
+	exitError = 1 //構文エラーや実行時エラー
+	exitUsage = 2 //コマンドラインの指定が間違っている
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr, isTerminal(os.Stdin)))
+}
+
+//monkey [-engine=eval|vm] [-overflow=promote|error] [-e 式 | ファイル]
+//ファイルも-eもなく、標準入力が端末でなければ標準入力をスクリプトとして実行する。
+//monkey fmtでソースを整形する("fmt"という名前のファイルを実行するには./fmtと書く)
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer, interactive bool) int {
+	if len(args) > 0 && args[0] == "fmt" {
+		return runFormat(args[1:], stdin, stdout, stderr)
+	}
+
+	flags := flag.NewFlagSet("monkey", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: monkey [flags] [file]")
+		fmt.Fprintln(stderr, "       monkey fmt [-w | -check] [-braces=same|next] [file ...]")
+		flags.PrintDefaults()
+	}
+	expr := flags.String("e", "", "evaluate `expr` and print the result")
+	engine := flags.String("engine", "eval", "execution engine: eval or vm")
+	overflow := flags.String("overflow", "promote", "integer overflow mode: promote or error")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	opts := options{engine: *engine}
+	switch *overflow {
+	case "promote":
+		opts.overflowMode = object.PromoteOnOverflow
+	case "error":
+		opts.overflowMode = object.ErrorOnOverflow
+	default:
+		fmt.Fprintf(stderr, "monkey: unknown overflow mode %q\n", *overflow)
+		return exitUsage
+	}
+	if opts.engine != "eval" && opts.engine != "vm" {
+		fmt.Fprintf(stderr, "monkey: unknown engine %q\n", opts.engine)
+		return exitUsage
+	}
+
+	exprSet := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "e" {
+			exprSet = true
+		}
+	})
+
+	switch {
+	case exprSet && flags.NArg() > 0:
+		fmt.Fprintln(stderr, "monkey: cannot use -e with a file")
+		return exitUsage
+	case flags.NArg() > 1:
+		fmt.Fprintln(stderr, "monkey: too many arguments")
+		return exitUsage
+	case exprSet:
+		opts.printResult = true
+		return execute("-e", *expr, opts, stdout, stderr)
+	case flags.NArg() == 1:
+		source, err := ioutil.ReadFile(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey: %s\n", err)
+			return exitError
+		}
+		return execute(flags.Arg(0), string(source), opts, stdout, stderr)
+	case !interactive:
+		source, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey: %s\n", err)
+			return exitError
+		}
+		return execute("<stdin>", string(source), opts, stdout, stderr)
+	default:
+		greet(stdout)
+		repl.Start(stdin, stdout)
+		return exitOK
+	}
+}
+
+type options struct {
+	engine       string
+	overflowMode object.OverflowMode
+	printResult  bool //-eのときは最後の式の値を表示する
+}
+
+//エラーは"名前:行:列: メッセージ"の形式で標準エラー出力に書く
+func execute(name, source string, opts options, stdout, stderr io.Writer) int {
+	l := lexer.New(lexer.StripShebang(source))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, e := range p.Errors() {
+			fmt.Fprintf(stderr, "%s:%s\n", name, e)
+		}
+		return exitError
+	}
+
+	macroEnv := object.NewEnvironment()
+	evaluator.DefineMacros(program, macroEnv)
+	expanded, err := evaluator.ExpandMacros(program, macroEnv)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: macro error: %s\n", name, err)
+		return exitError
+	}
+
+	object.Stdout = stdout
+	var result object.Object
+	if opts.engine == "vm" {
+		result = runVM(expanded, opts.overflowMode)
+	} else {
+		env := object.NewEnvironment()
+		env.SetOverflowMode(opts.overflowMode)
+		result = evaluator.Eval(expanded, env)
+	}
+
+	if errObj, ok := result.(*object.Error); ok {
+		return reportError(name, errObj, stderr)
+	}
+	if opts.printResult && result != nil && result.Type() != object.NULL_OBJ {
+		s, errObj := object.InspectSafely(result)
+		if errObj != nil {
+			return reportError(name, errObj, stderr)
+		}
+		fmt.Fprintln(stdout, s)
+	}
+	return exitOK
+}
+
+func reportError(name string, errObj *object.Error, stderr io.Writer) int {
+	fmt.Fprintf(stderr, "%s: %s\n", name, errObj.Inspect())
+	if errObj.Stack != "" {
+		io.WriteString(stderr, errObj.Stack)
+	}
+	return exitError
+}
+
+//コンパイルや実行のエラーは、評価器と同じくobject.Errorとして返す
+func runVM(program ast.Node, mode object.OverflowMode) object.Object {
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		return &object.Error{Message: err.Error()}
+	}
+
+	machine := vm.New(comp.Bytecode())
+	machine.SetOverflowMode(mode)
+	if err := machine.Run(); err != nil {
+		if errObj, ok := err.(*object.Error); ok {
+			return errObj
+		}
+		return &object.Error{Message: err.Error()}
+	}
+	return machine.LastPoppedStackElem()
+}
+
+//monkey fmt [-w | -check] [-braces=same|next] [ファイル...]
+//ファイルがなければ標準入力を整形して標準出力に書く。-wはファイルを書き換え、
+//-checkは整形されていないファイルの名前を書いて終了コード1で終わる
+func runFormat(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("monkey fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: monkey fmt [flags] [file ...]")
+		flags.PrintDefaults()
+	}
+	write := flags.Bool("w", false, "write the result to the file instead of stdout")
+	check := flags.Bool("check", false, "list files whose formatting differs and exit with status 1")
+	braces := flags.String("braces", "same", "place opening braces on the same line or the next line: same or next")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	config := format.DefaultConfig
+	switch *braces {
+	case "same":
+		config.Braces = format.SameLine
+	case "next":
+		config.Braces = format.NextLine
+	default:
+		fmt.Fprintf(stderr, "monkey fmt: unknown brace style %q\n", *braces)
+		return exitUsage
+	}
+	if *write && *check {
+		fmt.Fprintln(stderr, "monkey fmt: cannot use -w with -check")
+		return exitUsage
+	}
+	if *write && flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "monkey fmt: cannot use -w with standard input")
+		return exitUsage
+	}
+
+	if flags.NArg() == 0 {
+		source, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey: %s\n", err)
+			return exitError
+		}
+		return formatSource(config, "<stdin>", source, *check, stdout, stderr)
+	}
+
+	code := exitOK
+	for _, name := range flags.Args() {
+		source, err := ioutil.ReadFile(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey: %s\n", err)
+			code = exitError
+			continue
+		}
+		if *write {
+			if formatFile(config, name, source, stderr) != exitOK {
+				code = exitError
+			}
+		} else if formatSource(config, name, source, *check, stdout, stderr) != exitOK {
+			code = exitError
+		}
+	}
+	return code
+}
+
+//checkのときは、整形結果が元と違えば名前だけを書く
+func formatSource(config format.Config, name string, source []byte, check bool, stdout, stderr io.Writer) int {
+	formatted, ok := formatOrReport(config, name, source, stderr)
+	if !ok {
+		return exitError
+	}
+	if !check {
+		stdout.Write(formatted)
+		return exitOK
+	}
+	if !bytes.Equal(source, formatted) {
+		fmt.Fprintln(stdout, name)
+		return exitError
+	}
+	return exitOK
+}
+
+//整形済みのファイルは書き換えない
+func formatFile(config format.Config, name string, source []byte, stderr io.Writer) int {
+	formatted, ok := formatOrReport(config, name, source, stderr)
+	if !ok {
+		return exitError
+	}
+	if bytes.Equal(source, formatted) {
+		return exitOK
+	}
+	info, err := os.Stat(name)
+	if err == nil {
+		err = ioutil.WriteFile(name, formatted, info.Mode().Perm())
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "monkey: %s\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+//構文エラーは実行するときと同じく"名前:行:列: メッセージ"の形式で書く
+func formatOrReport(config format.Config, name string, source []byte, stderr io.Writer) ([]byte, bool) {
+	formatted, err := config.Source(source)
+	if syntaxErr, ok := err.(*format.SyntaxError); ok {
+		for _, e := range syntaxErr.Errors {
+			fmt.Fprintf(stderr, "%s:%s\n", name, e)
+		}
+		return nil, false
+	}
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(stderr, "%s: %s\n", name, err)
+		return nil, false
+	}
+	return formatted, true
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func greet(out io.Writer) {
+	name := "there"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
 	}
-	fmt.Printf("Hello %s! This is the Monkey programing lanuguage!\n",user.Username)
-	fmt.Printf("Fell free to type in commnads\n")
-	repl.Start(os.Stdin,os.Stdout)
+	fmt.Fprintf(out, "Hello %s! This is the Monkey programing lanuguage!\n", name)
+	fmt.Fprintf(out, "Fell free to type in commnads\n")
 }
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"interpreter-go/object"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "script.mk")
+	source := "#!/usr/bin/env monkey\nlet x = 2;\nputs(x * 21);\n"
+	if err := ioutil.WriteFile(script, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.mk")
+	if err := ioutil.WriteFile(broken, []byte("#!/usr/bin/env monkey\nlet = 1;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	//マクロの中で名前を付け替えた再帰関数は、VMでも自分の名前で呼び出せる
+	recursiveMacro := "let m = macro(x) { quote(fn() { let f = fn(n) { if (n == 0) { unquote(x) } else { f(n - 1) } }; f(3) }()) }; m(7)"
+
+	tests := []struct {
+		args           []string
+		stdin          string
+		expectedCode   int
+		expectedStdout string
+		expectedStderr string
+	}{
+		{[]string{script}, "", exitOK, "42\n", ""},
+		{[]string{"-engine=vm", script}, "", exitOK, "42\n", ""},
+		{[]string{broken}, "", exitError, "", broken + ":2:5: expected IDENT\n"},
+		{[]string{"-e", "1 + 2"}, "", exitOK, "3\n", ""},
+		{[]string{"-e", "let x = 1;"}, "", exitOK, "", ""},
+		{[]string{"-engine=vm", "-e", "let x = 1;"}, "", exitOK, "", ""},
+		{[]string{"-engine=vm", "-e", "for (x in [1]) { }"}, "", exitOK, "", ""},
+		{[]string{"-engine=vm", "-e", "while (false) { }"}, "", exitOK, "", ""},
+		{[]string{"-engine=vm", "-e", "1; for (x in [1]) { 2 }"}, "", exitOK, "", ""},
+		{[]string{"-e", "puts(1)"}, "", exitOK, "1\n", ""},
+		{[]string{"-e", "[if (true) {}]"}, "", exitOK, "[null]\n", ""},
+		{[]string{"-e", "puts(if (true) {})"}, "", exitOK, "null\n", ""},
+		{[]string{"-engine=vm", "-e", recursiveMacro}, "", exitOK, "7\n", ""},
+		{[]string{"-e", "1 / 0"}, "", exitError, "", "-e: ERROR: division by zero\n"},
+		{[]string{"-engine=vm", "-e", "1 / 0"}, "", exitError, "", "-e: ERROR: division by zero\n"},
+		{[]string{"-e", "9223372036854775807 + 1"}, "", exitOK, "9223372036854775808\n", ""},
+		{[]string{"-overflow=error", "-e", "9223372036854775807 + 1"}, "", exitError, "",
+			"-e: ERROR: integer overflow: 9223372036854775807 + 1\n"},
+		{[]string{"-overflow=error", "-engine=vm", "-e", "9223372036854775807 + 1"}, "", exitError, "",
+			"-e: ERROR: integer overflow: 9223372036854775807 + 1\n"},
+		{[]string{}, "#!monkey\nputs(\"piped\")", exitOK, "piped\n", ""},
+		{[]string{}, "foo", exitError, "", "<stdin>: ERROR: identifier not found: foo\n"},
+		{[]string{"-engine=js", "-e", "1"}, "", exitUsage, "", "monkey: unknown engine \"js\"\n"},
+		{[]string{"-overflow=wrap", "-e", "1"}, "", exitUsage, "", "monkey: unknown overflow mode \"wrap\"\n"},
+		{[]string{"-e", "1", script}, "", exitUsage, "", "monkey: cannot use -e with a file\n"},
+		{[]string{script, script}, "", exitUsage, "", "monkey: too many arguments\n"},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr, false)
+
+		if code != tt.expectedCode {
+			t.Errorf("%v: exit code wrong. expected=%d got=%d (stderr=%q)", tt.args, tt.expectedCode, code, stderr.String())
+		}
+		if stdout.String() != tt.expectedStdout {
+			t.Errorf("%v: stdout wrong. expected=%q got=%q", tt.args, tt.expectedStdout, stdout.String())
+		}
+		if stderr.String() != tt.expectedStderr {
+			t.Errorf("%v: stderr wrong. expected=%q got=%q", tt.args, tt.expectedStderr, stderr.String())
+		}
+	}
+}
+
+func TestRunMissingFile(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"no-such-file.mk"}, strings.NewReader(""), &stdout, &stderr, false)
+
+	if code != exitError {
+		t.Errorf("exit code wrong. expected=%d got=%d", exitError, code)
+	}
+	if !strings.HasPrefix(stderr.String(), "monkey: open no-such-file.mk:") {
+		t.Errorf("stderr wrong. got=%q", stderr.String())
+	}
+}
+
+//ホスト側の組み込み関数が新しく作ったBooleanやNullも、どちらのエンジンでも同じように扱われる
+func TestHostBooleanBuiltin(t *testing.T) {
+	err := object.RegisterBuiltin("isEven", 1, []object.ObjectType{object.INTEGER_OBJ}, func(args ...object.Object) object.Object {
+		return &object.Boolean{Value: args[0].(*object.Integer).Value%2 == 0}
+	})
+	if err != nil {
+		t.Fatalf("RegisterBuiltin returned error: %s", err)
+	}
+	err = object.RegisterBuiltin("nothing", 0, nil, func(args ...object.Object) object.Object {
+		return &object.Null{}
+	})
+	if err != nil {
+		t.Fatalf("RegisterBuiltin returned error: %s", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`if (isEven(3)) { "even" } else { "odd" }`, "odd\n"},
+		{`if (isEven(4)) { "even" } else { "odd" }`, "even\n"},
+		{"!isEven(3)", "true\n"},
+		{"isEven(3) == false", "true\n"},
+		{"isEven(4) != true", "false\n"},
+		{"isEven(3) && true", "false\n"},
+		{"[isEven(2)][0] == true", "true\n"},
+		{"nothing() == (if (false) { 1 })", "true\n"},
+		{"!nothing()", "true\n"},
+		{"nothing()", ""},
+	}
+
+	for _, engine := range []string{"eval", "vm"} {
+		for _, tt := range tests {
+			var stdout, stderr bytes.Buffer
+			code := run([]string{"-engine=" + engine, "-e", tt.input}, strings.NewReader(""), &stdout, &stderr, false)
+
+			if code != exitOK {
+				t.Errorf("%s: %q: exit code wrong. expected=%d got=%d (stderr=%q)", engine, tt.input, exitOK, code, stderr.String())
+			}
+			if stdout.String() != tt.expected {
+				t.Errorf("%s: %q: stdout wrong. expected=%q got=%q", engine, tt.input, tt.expected, stdout.String())
+			}
+		}
+	}
+}
+
+func TestRunFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	formatted := filepath.Join(dir, "formatted.mk")
+	if err := ioutil.WriteFile(formatted, []byte("let x = 1;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	messy := filepath.Join(dir, "messy.mk")
+	if err := ioutil.WriteFile(messy, []byte("let   x=1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.mk")
+	if err := ioutil.WriteFile(broken, []byte("let = 1;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args           []string
+		stdin          string
+		expectedCode   int
+		expectedStdout string
+		expectedStderr string
+	}{
+		{[]string{"fmt"}, "puts( 1 )", exitOK, "puts(1);\n", ""},
+		{[]string{"fmt", messy}, "", exitOK, "let x = 1;\n", ""},
+		{[]string{"fmt", "-check", formatted}, "", exitOK, "", ""},
+		{[]string{"fmt", "-check", formatted, messy}, "", exitError, messy + "\n", ""},
+		{[]string{"fmt", "-check"}, "let   x=1", exitError, "<stdin>\n", ""},
+		{[]string{"fmt", broken}, "", exitError, "", broken + ":1:5: expected IDENT\n"},
+		{[]string{"fmt", "-braces=next"}, "if (x) { 1 }", exitOK, "if (x)\n{\n    1;\n}\n", ""},
+		{[]string{"fmt", "-braces=kr"}, "", exitUsage, "", "monkey fmt: unknown brace style \"kr\"\n"},
+		{[]string{"fmt", "-w"}, "", exitUsage, "", "monkey fmt: cannot use -w with standard input\n"},
+		{[]string{"fmt", "-w", "-check", messy}, "", exitUsage, "", "monkey fmt: cannot use -w with -check\n"},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr, false)
+
+		if code != tt.expectedCode {
+			t.Errorf("%v: exit code wrong. expected=%d got=%d (stderr=%q)", tt.args, tt.expectedCode, code, stderr.String())
+		}
+		if stdout.String() != tt.expectedStdout {
+			t.Errorf("%v: stdout wrong. expected=%q got=%q", tt.args, tt.expectedStdout, stdout.String())
+		}
+		if stderr.String() != tt.expectedStderr {
+			t.Errorf("%v: stderr wrong. expected=%q got=%q", tt.args, tt.expectedStderr, stderr.String())
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"fmt", "-w", messy, formatted}, strings.NewReader(""), &stdout, &stderr, false); code != exitOK {
+		t.Fatalf("fmt -w: exit code wrong. expected=%d got=%d (stderr=%q)", exitOK, code, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("fmt -w should not write to stdout. got=%q", stdout.String())
+	}
+	for _, name := range []string{messy, formatted} {
+		source, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(source) != "let x = 1;\n" {
+			t.Errorf("%s: not formatted. got=%q", name, source)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package object
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+)
+
+//Typesに指定すると、どの型の引数でも受け付ける
+const ANY_OBJ ObjectType = "ANY"
+
+//組み込み関数の名前の表。
+//識別子が環境に見つからなかったときに評価器から参照される
+type Registry struct {
+	builtins map[string]*Builtin
+	names    []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{builtins: map[string]*Builtin{}}
+}
+
+//arityが負の場合は可変長引数として扱い、引数の数を確認しない。
+//typesがnilの場合は引数の型を確認しない。同じ名前で登録すると上書きする
+func (r *Registry) Register(name string, arity int, types []ObjectType, fn BuiltinFunction) error {
+	if name == "" {
+		return fmt.Errorf("builtin name must not be empty")
+	}
+	if fn == nil {
+		return fmt.Errorf("builtin %q has no function", name)
+	}
+	if types != nil && arity >= 0 && len(types) != arity {
+		return fmt.Errorf("builtin %q has %d argument types for arity %d", name, len(types), arity)
+	}
+
+	if _, ok := r.builtins[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.builtins[name] = &Builtin{Name: name, Arity: arity, Types: types, Fn: fn}
+	return nil
+}
+
+func (r *Registry) Lookup(name string) (*Builtin, bool) {
+	builtin, ok := r.builtins[name]
+	return builtin, ok
+}
+
+//登録した順番でindex番目の組み込み関数を返す。VMはこの番号で組み込み関数を参照する
+func (r *Registry) Get(index int) (*Builtin, bool) {
+	if index < 0 || index >= len(r.names) {
+		return nil, false
+	}
+	return r.builtins[r.names[index]], true
+}
+
+//登録した順番で名前を返す
+func (r *Registry) Names() []string {
+	names := make([]string, len(r.names))
+	copy(names, r.names)
+	return names
+}
+
+//ホスト側のGoのコードはここに関数を登録して、Monkeyから呼び出せるようにする
+var Builtins = NewRegistry()
+
+func RegisterBuiltin(name string, arity int, types []ObjectType, fn BuiltinFunction) error {
+	return Builtins.Register(name, arity, types, fn)
+}
+
+//putsの出力先。テストやホスト側で差し替えられる
+var Stdout io.Writer = os.Stdout
+
+//組み込み関数がnilを返したときは、評価器がNULLとして扱う。
+//配列を返す組み込み関数は、元の配列を変更せずに新しい配列を返す
+func init() {
+	mustRegister("len", 1, nil, func(args ...Object) Object {
+		switch arg := args[0].(type) {
+		case *String:
+			return &Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+		case *Array:
+			return &Integer{Value: int64(len(arg.Elements))}
+		default:
+			return newError("argument to `len` not supported, got %s", args[0].Type())
+		}
+	})
+	mustRegister("first", 1, []ObjectType{ARRAY_OBJ}, func(args ...Object) Object {
+		arr := args[0].(*Array)
+		if len(arr.Elements) > 0 {
+			return arr.Elements[0]
+		}
+		return nil
+	})
+	mustRegister("last", 1, []ObjectType{ARRAY_OBJ}, func(args ...Object) Object {
+		arr := args[0].(*Array)
+		length := len(arr.Elements)
+		if length > 0 {
+			return arr.Elements[length-1]
+		}
+		return nil
+	})
+	mustRegister("rest", 1, []ObjectType{ARRAY_OBJ}, func(args ...Object) Object {
+		arr := args[0].(*Array)
+		length := len(arr.Elements)
+		if length > 0 {
+			newElements := make([]Object, length-1)
+			copy(newElements, arr.Elements[1:length])
+			return &Array{Elements: newElements}
+		}
+		return nil
+	})
+	mustRegister("push", 2, []ObjectType{ARRAY_OBJ, ANY_OBJ}, func(args ...Object) Object {
+		arr := args[0].(*Array)
+		length := len(arr.Elements)
+		newElements := make([]Object, length+1)
+		copy(newElements, arr.Elements)
+		newElements[length] = args[1]
+		return &Array{Elements: newElements}
+	})
+	mustRegister("puts", -1, nil, func(args ...Object) Object {
+		for _, arg := range args {
+			fmt.Fprintln(Stdout, arg.Inspect())
+		}
+		return nil
+	})
+}
+
+func mustRegister(name string, arity int, types []ObjectType, fn BuiltinFunction) {
+	if err := Builtins.Register(name, arity, types, fn); err != nil {
+		panic(err)
+	}
+}
+
+func newError(format string, a ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, a...)}
+}
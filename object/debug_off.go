@@ -0,0 +1,7 @@
+//go:build !debug
+// +build !debug
+
+package object
+
+//go build -tags debugのときだけtrueになる
+const Debug = false
@@ -0,0 +1,113 @@
+package object
+
+import "sort"
+
+type Environment struct {
+	store map[string]Object
+	outer *Environment
+	macro bool //マクロ呼び出しの環境
+
+	overflowMode OverflowMode //一番外側の環境の値だけを使う
+	gensym       int          //マクロの展開で付け替えた名前の通し番号。これも一番外側の環境で数える
+	callDepth    int          //評価中の関数呼び出しの入れ子の深さ。これも一番外側の環境で数える
+}
+
+func NewEnvironment() *Environment {
+	return &Environment{store: map[string]Object{}}
+}
+
+//関数呼び出しやブロックのスコープ用。見つからない名前はouterに問い合わせる
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	return env
+}
+
+func (e *Environment) Get(name string) (Object, bool) {
+	obj, ok := e.store[name]
+	if !ok && e.outer != nil {
+		return e.outer.Get(name)
+	}
+	return obj, ok
+}
+
+func (e *Environment) Set(name string, val Object) Object {
+	e.store[name] = val
+	return val
+}
+
+//この環境に束縛された名前を辞書順で返す。outerの名前は含まない
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//一番近い環境にある束縛を書き換える。どこにも束縛がなければfalseを返す
+func (e *Environment) Assign(name string, val Object) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			env.store[name] = val
+			return true
+		}
+	}
+	return false
+}
+
+//マクロの本体を展開するときの環境
+func NewMacroEnvironment(outer *Environment) *Environment {
+	env := NewEnclosedEnvironment(outer)
+	env.macro = true
+	return env
+}
+
+func (e *Environment) ExpandingMacro() bool {
+	for env := e; env != nil; env = env.outer {
+		if env.macro {
+			return true
+		}
+	}
+	return false
+}
+
+//インタプリタ全体の設定なので、一番外側の環境に持たせる
+func (e *Environment) SetOverflowMode(mode OverflowMode) {
+	e.root().overflowMode = mode
+}
+
+func (e *Environment) OverflowMode() OverflowMode {
+	return e.root().overflowMode
+}
+
+//関数を呼び出す前に深さを1つ増やす。深さがlimitに達していたら増やさずにfalseを返す
+func (e *Environment) EnterCall(limit int) bool {
+	root := e.root()
+	if root.callDepth >= limit {
+		return false
+	}
+	root.callDepth++
+	return true
+}
+
+//EnterCallで増やした深さを、関数から戻るときに戻す
+func (e *Environment) LeaveCall() {
+	e.root().callDepth--
+}
+
+//マクロの展開ごとに、前と違う番号を返す
+func (e *Environment) NextGensym() int {
+	root := e.root()
+	root.gensym++
+	return root.gensym
+}
+
+func (e *Environment) root() *Environment {
+	env := e
+	for env.outer != nil {
+		env = env.outer
+	}
+	return env
+}
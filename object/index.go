@@ -0,0 +1,37 @@
+package object
+
+import "fmt"
+
+//left[index] = valueの代入。評価器とVMで同じエラーになるように、ここにまとめる。
+//負のインデックスは末尾から数え、配列の範囲外への代入はエラーにする
+func SetIndex(left, index, value Object) error {
+	switch left := left.(type) {
+	case *Array:
+		integer, ok := index.(*Integer)
+		if !ok {
+			if index.Type() == INTEGER_OBJ {
+				return fmt.Errorf("index out of range: %s", index.Inspect())
+			}
+			return fmt.Errorf("index assignment not supported: ARRAY[%s]", index.Type())
+		}
+		i := integer.Value
+		length := int64(len(left.Elements))
+		if i < 0 {
+			i += length
+		}
+		if i < 0 || i >= length {
+			return fmt.Errorf("index out of range: %d", integer.Value)
+		}
+		left.Elements[i] = value
+		return nil
+	case *Hash:
+		key, ok := index.(Hashable)
+		if !ok {
+			return fmt.Errorf("unusable as hash key: %s", index.Type())
+		}
+		left.Pairs[key.HashKey()] = HashPair{Key: index, Value: value}
+		return nil
+	default:
+		return fmt.Errorf("index assignment not supported: %s", left.Type())
+	}
+}
@@ -0,0 +1,256 @@
+package object
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/big"
+)
+
+//整数の演算結果がint64に収まらないときの扱い
+type OverflowMode int
+
+const (
+	//math/bigを使うBigIntに切り替える
+	PromoteOnOverflow OverflowMode = iota
+	//エラーにする
+	ErrorOnOverflow
+)
+
+//int64に収まらない整数。型は通常の整数と同じINTEGERとして扱う
+type BigInt struct {
+	Value *big.Int
+}
+
+func (b BigInt) Inspect() string { return b.Value.String() }
+
+func (b BigInt) Type() ObjectType { return INTEGER_OBJ }
+
+func (b BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(b.Value.String()))
+	return HashKey{Type: b.Type(), Value: h.Sum64()}
+}
+
+//int64に収まる値はInteger、収まらない値はBigIntにする
+func NewInteger(v *big.Int) Object {
+	if v.IsInt64() {
+		return &Integer{Value: v.Int64()}
+	}
+	return &BigInt{Value: v}
+}
+
+//int64に収まらない整数リテラルは、ErrorOnOverflowでは書いただけでオーバーフローとする
+func CheckBigIntegerLiteral(v *big.Int, mode OverflowMode) error {
+	if mode == ErrorOnOverflow {
+		return fmt.Errorf("integer overflow: %s", v)
+	}
+	return nil
+}
+
+//IntegerかBigIntの値をbig.Intで返す
+func ToBigInt(obj Object) (*big.Int, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return big.NewInt(obj.Value), true
+	case *BigInt:
+		return obj.Value, true
+	default:
+		return nil, false
+	}
+}
+
+//シフトできる最大のビット数。巨大なBigIntを作ってメモリを使い切らないようにする
+const MaxShift = 1 << 16
+
+//整数同士の算術演算とビット演算。結果がint64に収まらなければmodeに従う
+func IntegerArithmetic(operator string, left, right Object, mode OverflowMode) (Object, error) {
+	switch operator {
+	case "/", "%":
+		if isZero(right) {
+			return nil, fmt.Errorf("division by zero")
+		}
+	case "<<", ">>":
+		if count, ok := ToBigInt(right); ok && count.Sign() < 0 {
+			return nil, fmt.Errorf("negative shift count: %s", right.Inspect())
+		}
+	}
+
+	l, lok := left.(*Integer)
+	r, rok := right.(*Integer)
+	if lok && rok {
+		if result, ok := int64Arithmetic(operator, l.Value, r.Value); ok {
+			return &Integer{Value: result}, nil
+		}
+	}
+
+	lv, lok := ToBigInt(left)
+	rv, rok := ToBigInt(right)
+	if !lok || !rok {
+		return nil, fmt.Errorf("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+	}
+
+	result := new(big.Int)
+	switch operator {
+	case "+":
+		result.Add(lv, rv)
+	case "-":
+		result.Sub(lv, rv)
+	case "*":
+		result.Mul(lv, rv)
+	case "/":
+		result.Quo(lv, rv)
+	case "%":
+		result.Rem(lv, rv)
+	case "&":
+		result.And(lv, rv)
+	case "|":
+		result.Or(lv, rv)
+	case "^":
+		result.Xor(lv, rv)
+	case "<<", ">>":
+		if !rv.IsInt64() || rv.Int64() > MaxShift {
+			return nil, fmt.Errorf("shift count too large: %s", right.Inspect())
+		}
+		if operator == "<<" {
+			result.Lsh(lv, uint(rv.Int64()))
+		} else {
+			result.Rsh(lv, uint(rv.Int64()))
+		}
+	default:
+		return nil, fmt.Errorf("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+
+	if mode == ErrorOnOverflow && !result.IsInt64() {
+		return nil, fmt.Errorf("integer overflow: %s %s %s", left.Inspect(), operator, right.Inspect())
+	}
+	return NewInteger(result), nil
+}
+
+func isZero(obj Object) bool {
+	switch obj := obj.(type) {
+	case *Integer:
+		return obj.Value == 0
+	case *BigInt:
+		return obj.Value.Sign() == 0
+	default:
+		return false
+	}
+}
+
+//オーバーフローしたときはfalseを返す
+func int64Arithmetic(operator string, a, b int64) (int64, bool) {
+	switch operator {
+	case "+":
+		result := a + b
+		if (b > 0 && result < a) || (b < 0 && result > a) {
+			return 0, false
+		}
+		return result, true
+	case "-":
+		result := a - b
+		if (b > 0 && result > a) || (b < 0 && result < a) {
+			return 0, false
+		}
+		return result, true
+	case "*":
+		if a == 0 || b == 0 {
+			return 0, true
+		}
+		result := a * b
+		if result/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+			return 0, false
+		}
+		return result, true
+	case "/":
+		if a == math.MinInt64 && b == -1 {
+			return 0, false
+		}
+		return a / b, true
+	case "%":
+		return a % b, true
+	case "&":
+		return a & b, true
+	case "|":
+		return a | b, true
+	case "^":
+		return a ^ b, true
+	case "<<":
+		if b >= 63 {
+			return 0, false
+		}
+		result := a << uint(b)
+		if result>>uint(b) != a {
+			return 0, false
+		}
+		return result, true
+	case ">>":
+		if b >= 63 {
+			return a >> 63, true
+		}
+		return a >> uint(b), true
+	default:
+		return 0, false
+	}
+}
+
+func NegateInteger(obj Object, mode OverflowMode) (Object, error) {
+	if i, ok := obj.(*Integer); ok && i.Value != math.MinInt64 {
+		return &Integer{Value: -i.Value}, nil
+	}
+
+	v, ok := ToBigInt(obj)
+	if !ok {
+		return nil, fmt.Errorf("unknown operator: -%s", obj.Type())
+	}
+	result := new(big.Int).Neg(v)
+	if mode == ErrorOnOverflow && !result.IsInt64() {
+		return nil, fmt.Errorf("integer overflow: -(%s)", obj.Inspect())
+	}
+	return NewInteger(result), nil
+}
+
+//~x。結果は必ず元と同じ範囲に収まる
+func BitNotInteger(obj Object) (Object, error) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return &Integer{Value: ^obj.Value}, nil
+	case *BigInt:
+		return NewInteger(new(big.Int).Not(obj.Value)), nil
+	default:
+		return nil, fmt.Errorf("unknown operator: ~%s", obj.Type())
+	}
+}
+
+//left < rightなら-1、等しければ0、left > rightなら1
+func CompareIntegers(left, right Object) int {
+	l, lok := left.(*Integer)
+	r, rok := right.(*Integer)
+	if lok && rok {
+		switch {
+		case l.Value < r.Value:
+			return -1
+		case l.Value > r.Value:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	lv, _ := ToBigInt(left)
+	rv, _ := ToBigInt(right)
+	return lv.Cmp(rv)
+}
+
+//浮動小数点数との演算用。BigIntは一番近い値に丸める
+func IntegerToFloat(obj Object) float64 {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value)
+	case *BigInt:
+		f, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return f
+	default:
+		return 0
+	}
+}
@@ -1,6 +1,16 @@
 package object
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"interpreter-go/ast"
+	"interpreter-go/code"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+)
 
 type ObjectType string
 
@@ -11,10 +21,23 @@ type Object interface {
 
 const (
 	INTEGER_OBJ      ObjectType = "INTEGER"
+	FLOAT_OBJ        ObjectType = "FLOAT"
 	BOOLEAN_OBJ      ObjectType = "BOOLEAN"
 	NULL_OBJ         ObjectType = "NULL"
 	RETURN_VALUE_OBJ ObjectType = "RETURN_VALUE"
 	ERROR_OBJ        ObjectType = "ERROR"
+	FUNCTION_OBJ     ObjectType = "FUNCTION"
+	STRING_OBJ       ObjectType = "STRING"
+	BUILTIN_OBJ      ObjectType = "BUILTIN"
+	ARRAY_OBJ        ObjectType = "ARRAY"
+	HASH_OBJ         ObjectType = "HASH"
+	QUOTE_OBJ        ObjectType = "QUOTE"
+	MACRO_OBJ        ObjectType = "MACRO"
+	BREAK_OBJ        ObjectType = "BREAK"
+	CONTINUE_OBJ     ObjectType = "CONTINUE"
+
+	COMPILED_FUNCTION_OBJ ObjectType = "COMPILED_FUNCTION"
+	CELL_OBJ              ObjectType = "CELL"
 )
 
 type Integer struct {
@@ -25,6 +48,25 @@ func (i Integer) Inspect() string { return fmt.Sprintf("%d", i.Value) }
 
 func (i Integer) Type() ObjectType { return INTEGER_OBJ }
 
+func (i Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+type Float struct {
+	Value float64
+}
+
+//整数と見分けられるように、小数部がなくても"1.0"のように表示する
+func (f Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if strings.ContainsAny(s, ".eIN") {
+		return s
+	}
+	return s + ".0"
+}
+
+func (f Float) Type() ObjectType { return FLOAT_OBJ }
+
 type Boolean struct {
 	Value bool
 }
@@ -33,12 +75,49 @@ func (b Boolean) Inspect() string { return fmt.Sprintf("%t", b.Value) }
 
 func (b Boolean) Type() ObjectType { return BOOLEAN_OBJ }
 
+func (b Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+type String struct {
+	Value string
+}
+
+func (s String) Inspect() string { return s.Value }
+
+func (s String) Type() ObjectType { return STRING_OBJ }
+
+func (s String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
 type Null struct{}
 
 func (n Null) Inspect() string { return "null" }
 
 func (n Null) Type() ObjectType { return NULL_OBJ }
 
+//==と!=で、数値と文字列以外を比べる。真偽値とnullは値で比べるので、
+//ホストの組み込み関数が新しく作ったBooleanも評価器やVMの真偽値と等しくなる
+func Equal(left, right Object) bool {
+	switch left := left.(type) {
+	case *Boolean:
+		right, ok := right.(*Boolean)
+		return ok && left.Value == right.Value
+	case *Null:
+		_, ok := right.(*Null)
+		return ok
+	default:
+		return left == right
+	}
+}
+
 type ReturnValue struct {
 	Value Object
 }
@@ -47,10 +126,265 @@ func (rv ReturnValue) Inspect() string { return rv.Value.Inspect() }
 
 func (rv ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 
+//breakとcontinueも、ReturnValueと同じようにループまでブロックを抜けていく
+type Break struct{}
+
+func (b Break) Inspect() string { return "break" }
+
+func (b Break) Type() ObjectType { return BREAK_OBJ }
+
+type Continue struct{}
+
+func (c Continue) Inspect() string { return "continue" }
+
+func (c Continue) Type() ObjectType { return CONTINUE_OBJ }
+
 type Error struct {
 	Message string
+	Stack   string //Goのpanicから作ったとき、debugビルドならスタックトレースが入る
 }
 
 func (e Error) Inspect() string { return "ERROR: " + e.Message }
 
 func (e Error) Type() ObjectType { return ERROR_OBJ }
+
+//VMのようにerrorを返すところでも、Stackを失わずに返せるようにする
+func (e Error) Error() string { return e.Message }
+
+//評価中に起きたGoのpanicをErrorに変換する。recover()の戻り値を渡す
+func NewPanicError(r interface{}) *Error {
+	err := &Error{Message: fmt.Sprintf("internal error: %v", r)}
+	if Debug {
+		err.Stack = string(debug.Stack())
+	}
+	return err
+}
+
+//結果を表示するときにGoのpanicが起きても、評価中と同じくErrorとして返す
+func InspectSafely(obj Object) (s string, err *Error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewPanicError(r)
+		}
+	}()
+	return obj.Inspect(), nil
+}
+
+//Envは関数が定義された時点の環境。クロージャとして呼び出し時にも参照する
+type Function struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (f Function) Inspect() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range f.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("fn(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(f.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
+
+func (f Function) Type() ObjectType { return FUNCTION_OBJ }
+
+type BuiltinFunction func(args ...Object) Object
+
+type Builtin struct {
+	Name  string
+	Arity int
+	Types []ObjectType
+	Fn    BuiltinFunction
+}
+
+func (b Builtin) Inspect() string { return "builtin function" }
+
+func (b Builtin) Type() ObjectType { return BUILTIN_OBJ }
+
+//引数の数と型を確認してからFnを呼び出す
+func (b Builtin) Call(args ...Object) Object {
+	if b.Arity >= 0 && len(args) != b.Arity {
+		return newError("wrong number of arguments: want=%d, got=%d", b.Arity, len(args))
+	}
+
+	for i, expected := range b.Types {
+		if i >= len(args) {
+			break
+		}
+		if expected == ANY_OBJ || args[i].Type() == expected {
+			continue
+		}
+		if len(b.Types) == 1 {
+			return newError("argument to `%s` must be %s, got %s", b.Name, expected, args[i].Type())
+		}
+		return newError("argument %d to `%s` must be %s, got %s", i+1, b.Name, expected, args[i].Type())
+	}
+
+	return b.Fn(args...)
+}
+
+type Array struct {
+	Elements []Object
+}
+
+func (a Array) Inspect() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, e.Inspect())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+func (a Array) Type() ObjectType { return ARRAY_OBJ }
+
+//ハッシュのキーに使えるオブジェクトが実装する
+type Hashable interface {
+	HashKey() HashKey
+}
+
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+type Hash struct {
+	Pairs map[HashKey]HashPair
+}
+
+//mapの順番は毎回変わるので、表示はキーの文字列順に並べる
+func (h Hash) Inspect() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
+	}
+	sort.Strings(pairs)
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+func (h Hash) Type() ObjectType { return HASH_OBJ }
+
+//for inで順に取り出す要素。配列は要素、ハッシュはキー、文字列は1文字ずつの文字列。
+//ハッシュのキーはInspectの順に並べる
+func Elements(obj Object) ([]Object, bool) {
+	switch obj := obj.(type) {
+	case *Array:
+		return obj.Elements, true
+	case *Hash:
+		keys := make([]Object, 0, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			keys = append(keys, pair.Key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i].Inspect() < keys[j].Inspect() })
+		return keys, true
+	case *String:
+		chars := []Object{}
+		for _, r := range obj.Value {
+			chars = append(chars, &String{Value: string(r)})
+		}
+		return chars, true
+	default:
+		return nil, false
+	}
+}
+
+//コンパイラが関数リテラルから作る。VMではClosureに包んで使う
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	NumLocals     int
+	NumParameters int
+	Cells         []int //内側の関数が捕まえるローカル変数のインデックス。呼び出すときにセルに入れる
+
+	LocalNames []string //値を入れる前に読まれた変数を報告するための名前
+	FreeNames  []string
+}
+
+func (cf CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", &cf)
+}
+
+func (cf CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
+
+//Freeはクロージャが捕まえた自由変数。外側の関数と同じセルを共有する
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []*Cell
+}
+
+func (c Closure) Inspect() string {
+	return fmt.Sprintf("Closure[%p]", c.Fn)
+}
+
+//利用者から見ると評価器のFunctionと同じ関数なので、同じ型名にする
+func (c Closure) Type() ObjectType { return FUNCTION_OBJ }
+
+//VMで、外側の関数とクロージャの両方から書き換えられるローカル変数の入れ物。
+//評価器では環境を共有するので使わない
+type Cell struct {
+	Value Object
+}
+
+func (c *Cell) Inspect() string  { return c.Value.Inspect() }
+func (c *Cell) Type() ObjectType { return CELL_OBJ }
+
+//quoteで評価せずに包んだAST
+type Quote struct {
+	Node ast.Node
+}
+
+func (q Quote) Inspect() string {
+	return "QUOTE(" + q.Node.String() + ")"
+}
+
+func (q Quote) Type() ObjectType { return QUOTE_OBJ }
+
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (m Macro) Inspect() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range m.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("macro(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(m.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
+
+func (m Macro) Type() ObjectType { return MACRO_OBJ }
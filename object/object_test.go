@@ -0,0 +1,174 @@
+package object
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStringHashKey(t *testing.T) {
+	hello1 := &String{Value: "Hello World"}
+	hello2 := &String{Value: "Hello World"}
+	diff1 := &String{Value: "My name is johnny"}
+	diff2 := &String{Value: "My name is johnny"}
+
+	if hello1.HashKey() != hello2.HashKey() {
+		t.Errorf("strings with same content have different hash keys")
+	}
+
+	if diff1.HashKey() != diff2.HashKey() {
+		t.Errorf("strings with same content have different hash keys")
+	}
+
+	if hello1.HashKey() == diff1.HashKey() {
+		t.Errorf("strings with different content have same hash keys")
+	}
+}
+
+func TestHashKeyTypes(t *testing.T) {
+	one := &Integer{Value: 1}
+	truth := &Boolean{Value: true}
+
+	if one.HashKey() == truth.HashKey() {
+		t.Errorf("integer and boolean with same value have same hash keys")
+	}
+}
+
+func TestRegistryRegister(t *testing.T) {
+	noop := func(args ...Object) Object { return nil }
+
+	tests := []struct {
+		name          string
+		arity         int
+		types         []ObjectType
+		fn            BuiltinFunction
+		expectedError string
+	}{
+		{"", 0, nil, noop, "builtin name must not be empty"},
+		{"f", 0, nil, nil, `builtin "f" has no function`},
+		{"f", 2, []ObjectType{ANY_OBJ}, noop, `builtin "f" has 1 argument types for arity 2`},
+		{"f", -1, []ObjectType{STRING_OBJ}, noop, ""},
+	}
+
+	for _, tt := range tests {
+		r := NewRegistry()
+		err := r.Register(tt.name, tt.arity, tt.types, tt.fn)
+		if tt.expectedError == "" {
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%v", tt.expectedError, err)
+		}
+	}
+}
+
+func TestRegistryNames(t *testing.T) {
+	noop := func(args ...Object) Object { return nil }
+
+	r := NewRegistry()
+	r.Register("b", 0, nil, noop)
+	r.Register("a", 0, nil, noop)
+	r.Register("b", 1, nil, noop)
+
+	names := r.Names()
+	if len(names) != 2 || names[0] != "b" || names[1] != "a" {
+		t.Errorf("wrong names. got=%v", names)
+	}
+
+	b, ok := r.Lookup("b")
+	if !ok || b.Arity != 1 {
+		t.Errorf("builtin was not overwritten. got=%+v", b)
+	}
+}
+
+func TestBuiltinCall(t *testing.T) {
+	r := NewRegistry()
+	r.Register("sum", -1, []ObjectType{INTEGER_OBJ}, func(args ...Object) Object {
+		var total int64
+		for _, a := range args {
+			total += a.(*Integer).Value
+		}
+		return &Integer{Value: total}
+	})
+	sum, _ := r.Lookup("sum")
+
+	result := sum.Call(&Integer{Value: 1}, &Integer{Value: 2}, &Integer{Value: 3})
+	if integer, ok := result.(*Integer); !ok || integer.Value != 6 {
+		t.Errorf("wrong result. got=%T(%+v)", result, result)
+	}
+
+	result = sum.Call(&String{Value: "1"})
+	errObj, ok := result.(*Error)
+	if !ok {
+		t.Fatalf("result is not Error. got=%T(%+v)", result, result)
+	}
+	if errObj.Message != "argument to `sum` must be INTEGER, got STRING" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{3.14, "3.14"},
+		{1, "1.0"},
+		{-2, "-2.0"},
+		{0.25, "0.25"},
+		{1e-9, "1e-09"},
+		{1e21, "1e+21"},
+		{math.Inf(1), "+Inf"},
+		{math.NaN(), "NaN"},
+	}
+
+	for _, tt := range tests {
+		f := &Float{Value: tt.value}
+		if f.Inspect() != tt.expected {
+			t.Errorf("wrong Inspect. got=%q, want=%q", f.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestEnvironmentNames(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("z", &Integer{Value: 1})
+	env := NewEnclosedEnvironment(outer)
+	env.Set("b", &Integer{Value: 2})
+	env.Set("a", &Integer{Value: 3})
+
+	names := env.Names()
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("names wrong. got=%v", names)
+	}
+}
+
+func TestSetIndex(t *testing.T) {
+	array := &Array{Elements: []Object{&Integer{Value: 1}, &Integer{Value: 2}}}
+	if err := SetIndex(array, &Integer{Value: -1}, &Integer{Value: 5}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := array.Inspect(); got != "[1, 5]" {
+		t.Errorf("array wrong. got=%s", got)
+	}
+
+	tests := []struct {
+		left     Object
+		index    Object
+		expected string
+	}{
+		{array, &Integer{Value: 2}, "index out of range: 2"},
+		{array, &String{Value: "x"}, "index assignment not supported: ARRAY[STRING]"},
+		{&Hash{Pairs: map[HashKey]HashPair{}}, array, "unusable as hash key: ARRAY"},
+		{&String{Value: "abc"}, &Integer{Value: 0}, "index assignment not supported: STRING"},
+	}
+
+	for _, tt := range tests {
+		err := SetIndex(tt.left, tt.index, &Integer{Value: 0})
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("error wrong. expected=%q, got=%v", tt.expected, err)
+		}
+	}
+}
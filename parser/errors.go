@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"fmt"
+	"interpreter-go/token"
+)
+
+//これ以上のエラーが出たら構文解析を打ち切る
+const MaxErrors = 10
+
+type ParseError struct {
+	Pos      token.Position
+	Expected token.TokenType //期待したトークンがある場合のみ
+	Found    token.Token
+	Message  string
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+//文の区切りとして同期できるキーワード
+var statementStarts = map[token.TokenType]bool{
+	token.LET:      true,
+	token.RETURN:   true,
+	token.WHILE:    true,
+	token.FOR:      true,
+	token.BREAK:    true,
+	token.CONTINUE: true,
+}
+
+//エラーから回復するまでは、後続のエラーは連鎖したものとして記録しない
+func (p *Parser) addError(err *ParseError) {
+	if p.panicking || p.tooManyErrors() {
+		return
+	}
+	p.panicking = true
+
+	if len(p.errors) == MaxErrors-1 {
+		err = &ParseError{Pos: err.Pos, Found: err.Found, Message: "too many errors"}
+	}
+	p.errors = append(p.errors, err)
+}
+
+func (p Parser) tooManyErrors() bool {
+	return len(p.errors) >= MaxErrors
+}
+
+//エラーになった文の残りを読み飛ばす。
+//baseは文の開始時点のブレースの深さで、深さがbaseに戻った位置の';'か、
+//次の文の先頭か、囲んでいるブロックの'}'の手前で止まる
+func (p *Parser) synchronize(base int) {
+	for !p.curTokenIs(token.EOF) {
+		depth := p.depth
+		switch p.curToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			depth--
+		}
+
+		if depth < base {
+			//囲んでいるブロックの'}'まで読んでしまった
+			p.blockClosed = true
+			return
+		}
+		if depth == base {
+			if p.curTokenIs(token.SEMICOLON) {
+				return
+			}
+			if statementStarts[p.peekToken.Type] || p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF) {
+				return
+			}
+		}
+		p.nextToken()
+	}
+}
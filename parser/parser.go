@@ -1,39 +1,67 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"interpreter-go/ast"
 	"interpreter-go/lexer"
 	"interpreter-go/token"
+	"math/big"
 	"strconv"
+	"strings"
 )
 
+//ビット演算子はGoと同じく、&と<< >>を*と、|と^を+と同じ優先度にする
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN
+	LOGICAL_OR
+	LOGICAL_AND
 	EQUALS
 	LESSGRATER
 	SUM
 	PRODUCT
 	PREFIX
 	CALL
+	INDEX
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGRATER,
-	token.RT:       LESSGRATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.ASTERISK: PRODUCT,
-	token.SLASH:    PRODUCT,
-	token.LPAREN:   CALL,
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+	token.OR:              LOGICAL_OR,
+	token.AND:             LOGICAL_AND,
+	token.EQ:              EQUALS,
+	token.NOT_EQ:          EQUALS,
+	token.LT:              LESSGRATER,
+	token.RT:              LESSGRATER,
+	token.LT_EQ:           LESSGRATER,
+	token.RT_EQ:           LESSGRATER,
+	token.PLUS:            SUM,
+	token.MINUS:           SUM,
+	token.BIT_OR:          SUM,
+	token.BIT_XOR:         SUM,
+	token.ASTERISK:        PRODUCT,
+	token.SLASH:           PRODUCT,
+	token.PERCENT:         PRODUCT,
+	token.BIT_AND:         PRODUCT,
+	token.SHIFT_LEFT:      PRODUCT,
+	token.SHIFT_RIGHT:     PRODUCT,
+	token.LPAREN:          CALL,
+	token.LBRACKET:        INDEX,
 }
 
 type Parser struct {
 	lexer          *lexer.Lexer
-	errors         []string
+	errors         []*ParseError
+	panicking      bool //エラーの後、同期するまでtrue
+	depth          int  //curTokenより前の未対応の'{'の数
+	blockClosed    bool //回復中にブロックの'}'まで読み進めた
+	loopDepth      int  //今いるループの深さ。関数リテラルの中では0からやり直す
 	curToken       token.Token
 	peekToken      token.Token
 	prefixParseFns map[token.TokenType]prefixParseFn
@@ -43,21 +71,28 @@ type Parser struct {
 func New(lexer lexer.Lexer) *Parser {
 	p := &Parser{
 		lexer:          &lexer,
-		errors:         []string{},
+		errors:         []*ParseError{},
 		prefixParseFns: map[token.TokenType]prefixParseFn{},
 		infixParseFns:  map[token.TokenType]infixParseFn{},
 	}
 	p.nextToken()
 	p.nextToken()
+	p.registerPrefix(token.ILEEGAL, p.parseIllegal)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.BIT_NOT, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
@@ -67,38 +102,91 @@ func New(lexer lexer.Lexer) *Parser {
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.RT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_AND, p.parseInfixExpression)
+	p.registerInfix(token.BIT_OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_XOR, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_LEFT, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_RIGHT, p.parseInfixExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 
 	return p
 }
 
 func (p *Parser) nextToken() {
+	switch p.curToken.Type {
+	case token.LBRACE:
+		p.depth++
+	case token.RBRACE:
+		if p.depth > 0 {
+			p.depth--
+		}
+	}
 	p.curToken = p.peekToken
 	p.peekToken = p.lexer.NextToken()
+	//NewWithCommentsで作ったLexerを渡されても、コメントは構文に関係しないので読み飛ばす
+	for p.peekToken.Type == token.COMMENT {
+		p.peekToken = p.lexer.NextToken()
+	}
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 
 	for {
-		if p.curToken.Type == token.EOF {
+		if p.curToken.Type == token.EOF || p.tooManyErrors() {
 			break
 		}
 		statement := p.ParseStatement()
 		if statement != nil {
 			program.Statements = append(program.Statements, statement)
 		}
+		p.blockClosed = false
 		p.nextToken()
 	}
 	return program
 }
 
+//エラーになった文は、残りを読み飛ばしてnilを返す。
+//外側の文で既にエラーになっている場合は、外側の文に回復を任せる
 func (p *Parser) ParseStatement() ast.Statement {
+	if p.panicking {
+		return p.parseStatement()
+	}
+	base := p.depth
+	statement := p.parseStatement()
+	if p.panicking {
+		p.synchronize(base)
+		p.panicking = false
+		return nil
+	}
+	return statement
+}
+
+func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case token.LET:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.BREAK:
+		return p.parseBreakStatement()
+	case token.CONTINUE:
+		return p.parseContinueStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -122,6 +210,10 @@ func (p *Parser) parseLetStatement() *ast.LetStatementNode {
 
 	letSmt.Value = p.parseExpression(LOWEST)
 
+	if fl, ok := letSmt.Value.(*ast.FunctionLiteral); ok {
+		fl.Name = letSmt.Name.Value
+	}
+
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
@@ -144,6 +236,111 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatementNode {
 	return returnSmt
 }
 
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	whileSmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+
+	whileSmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	whileSmt.Body = p.parseLoopBody()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return whileSmt
+}
+
+//for (x in coll) { ... }
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	forSmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	forSmt.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+	p.nextToken()
+
+	forSmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	forSmt.Body = p.parseLoopBody()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return forSmt
+}
+
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+	return p.parseBlockStatement()
+}
+
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	breakSmt := &ast.BreakStatement{Token: p.curToken}
+	if !p.checkInLoop() {
+		return nil
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return breakSmt
+}
+
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	continueSmt := &ast.ContinueStatement{Token: p.curToken}
+	if !p.checkInLoop() {
+		return nil
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return continueSmt
+}
+
+//breakとcontinueはループの中でしか書けない
+func (p *Parser) checkInLoop() bool {
+	if p.loopDepth > 0 {
+		return true
+	}
+	p.addError(&ParseError{
+		Pos:     p.curToken.Pos,
+		Found:   p.curToken,
+		Message: fmt.Sprintf("%s outside loop", p.curToken.Literal),
+	})
+	return false
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	expressionSmt := &ast.ExpressionStatement{}
 	expressionSmt.Token = p.curToken
@@ -189,6 +386,32 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return &expression
 }
 
+//代入は右結合なので、右辺は一つ低い優先度で読む。a = b = 1はa = (b = 1)になる
+func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Target:   left,
+	}
+
+	switch left.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	case nil:
+		return nil //左辺で既にエラーになっている
+	default:
+		p.addError(&ParseError{
+			Pos:     left.Pos(),
+			Found:   p.curToken,
+			Message: fmt.Sprintf("invalid assignment target: %s", left.String()),
+		})
+		return nil
+	}
+
+	p.nextToken()
+	expression.Value = p.parseExpression(ASSIGN - 1)
+	return expression
+}
+
 func (p *Parser) parseGroupedExpression() ast.Expression {
 	p.nextToken()
 	//優先度（右結合力（先の演算子・オペランドが現在の式と結合する））を下げて、左結合すること避けている  1 * (2 + 3)
@@ -210,8 +433,25 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addError(&ParseError{
+		Pos:     p.curToken.Pos,
+		Found:   p.curToken,
+		Message: fmt.Sprintf("no prefix parse function for %s found", t),
+	})
+}
+
+//字句解析のエラーは、Lexerが付けた理由をトークンの位置で報告する
+func (p *Parser) parseIllegal() ast.Expression {
+	if p.curToken.Message == "" {
+		p.noPrefixParseFnError(p.curToken.Type)
+		return nil
+	}
+	p.addError(&ParseError{
+		Pos:     p.curToken.Pos,
+		Found:   p.curToken,
+		Message: p.curToken.Message,
+	})
+	return nil
 }
 
 func (p *Parser) parseIdentifier() ast.Expression {
@@ -223,13 +463,64 @@ func (p *Parser) parseBoolean() ast.Expression {
 }
 
 func (p *Parser) parseIntegerLiteral() ast.Expression {
-	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	digits, base, ok := integerDigits(p.curToken.Literal)
+	if ok {
+		value, err := strconv.ParseInt(digits, base, 64)
+		if err == nil {
+			return &ast.IntegerLiteral{Token: p.curToken, Value: value}
+		}
+		//int64に収まらないリテラルはBigIntとして扱う
+		if errors.Is(err, strconv.ErrRange) {
+			if bigValue, ok := new(big.Int).SetString(digits, base); ok {
+				return &ast.IntegerLiteral{Token: p.curToken, Big: bigValue}
+			}
+		}
+	}
+
+	p.addError(&ParseError{
+		Pos:     p.curToken.Pos,
+		Found:   p.curToken,
+		Message: fmt.Sprintf("could not parse %q as integer", p.curToken.Literal),
+	})
+	return nil
+}
+
+//0x, 0o, 0bの接頭辞を取り除いた数字と基数を返す。接頭辞がなければ、0で始まっていても10進とする。
+//'_'は数字の間の区切りとして取り除く。連続した'_'や末尾の'_'は不正なリテラルとしてfalseを返す
+func integerDigits(lit string) (string, int, bool) {
+	digits, base := lit, 10
+	if len(lit) >= 2 && lit[0] == '0' {
+		switch lit[1] {
+		case 'x', 'X':
+			digits, base = lit[2:], 16
+		case 'o', 'O':
+			digits, base = lit[2:], 8
+		case 'b', 'B':
+			digits, base = lit[2:], 2
+		}
+	}
+
+	if digits == "" || strings.HasSuffix(digits, "_") || strings.Contains(digits, "__") {
+		return "", 0, false
+	}
+	return strings.ReplaceAll(digits, "_", ""), base, true
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(&ParseError{
+			Pos:     p.curToken.Pos,
+			Found:   p.curToken,
+			Message: fmt.Sprintf("could not parse %q as float", p.curToken.Literal),
+		})
 		return nil
 	}
-	return &ast.IntegerLiteral{Token: p.curToken, Value: value}
+	return &ast.FloatLiteral{Token: p.curToken, Value: value}
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
 func (p *Parser) parsePrefixExpression() ast.Expression {
@@ -284,15 +575,29 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 
 	p.nextToken()
 
-	//EOFは、無限ループしないように
-	//閉じタグ見ていないから、チェック甘いかも？
-	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+	//EOFで止めて、閉じていないブロックとして報告する
+	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) && !p.tooManyErrors() {
 		stmt := p.ParseStatement()
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
+		if p.blockClosed {
+			p.blockClosed = false
+			break
+		}
 		p.nextToken()
 	}
+	if p.curTokenIs(token.RBRACE) {
+		block.Rbrace = p.curToken
+	}
+	if p.curTokenIs(token.EOF) {
+		p.addError(&ParseError{
+			Pos:      p.curToken.Pos,
+			Expected: token.RBRACE,
+			Found:    p.curToken,
+			Message:  fmt.Sprintf("expected %s", token.RBRACE),
+		})
+	}
 	return &block
 }
 
@@ -311,6 +616,30 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
+	//関数の中から外側のループをbreakすることはできない
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
+	expression.Body = p.parseBlockStatement()
+	p.loopDepth = loopDepth
+
+	return &expression
+}
+
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	expression := ast.MacroLiteral{
+		Token: p.curToken,
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	expression.Parameters = p.parseFunctionParameters()
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
 	expression.Body = p.parseBlockStatement()
 
 	return &expression
@@ -324,14 +653,16 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 		return identifieres
 	}
 
-	p.nextToken()
-
-	//次がtoken.Identifierのチェックが甘いかも？
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
 	identifieres = append(identifieres, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
-		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
 		identifieres = append(identifieres, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
 	}
 
@@ -344,31 +675,89 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 
 func (p *Parser) parseCallExpression(exp ast.Expression) ast.Expression {
 	callExpression := ast.CallExpression{Token: p.curToken, Function: exp}
-	callExpression.Arguments = p.parseCallArguments()
+	callExpression.Arguments = p.parseExpressionList(token.RPAREN)
+	if p.curTokenIs(token.RPAREN) {
+		callExpression.Rparen = p.curToken
+	}
 	return &callExpression
 }
 
-func (p *Parser) parseCallArguments() []ast.Expression {
-	argumesnts := []ast.Expression{}
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if p.curTokenIs(token.RBRACKET) {
+		array.Rbracket = p.curToken
+	}
+	return &array
+}
 
-	if p.peekTokenIs(token.RPAREN) {
+//関数呼び出しの引数と配列の要素で共通。endの位置までカンマ区切りの式を読む
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+
+	if p.peekTokenIs(end) {
 		p.nextToken()
-		return argumesnts
+		return list
 	}
 	p.nextToken()
-	argumesnts = append(argumesnts, p.parseExpression(LOWEST))
+	list = append(list, p.parseExpression(LOWEST))
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		argumesnts = append(argumesnts, p.parseExpression(LOWEST))
+		list = append(list, p.parseExpression(LOWEST))
 	}
 
-	if !p.expectPeek(token.RPAREN) {
+	if !p.expectPeek(end) {
 		return nil
 	}
 
-	return argumesnts
+	return list
+}
+
+//式の位置に来た'{'はハッシュリテラルとして読む。ブロックはif/fnの後でparseBlockStatementが読む
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := ast.HashLiteral{Token: p.curToken}
+	hash.Pairs = []ast.HashPair{}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	hash.Rbrace = p.curToken
+
+	return &hash
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	expression := ast.IndexExpression{Token: p.curToken, Left: left}
+
+	p.nextToken()
+	expression.Index = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	expression.Rbracket = p.curToken
+
+	return &expression
 }
 
 func (p *Parser) expectPeek(t token.TokenType) bool {
@@ -382,11 +771,24 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 }
 
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token %s,got %s", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(&ParseError{
+		Pos:      p.peekToken.Pos,
+		Expected: t,
+		Found:    p.peekToken,
+		Message:  fmt.Sprintf("expected %s", t),
+	})
 }
 
+//"行:列: メッセージ"の形式のエラーを返す
 func (p Parser) Errors() []string {
+	errors := []string{}
+	for _, e := range p.errors {
+		errors = append(errors, e.Error())
+	}
+	return errors
+}
+
+func (p Parser) ParseErrors() []*ParseError {
 	return p.errors
 }
 
@@ -168,6 +168,119 @@ func TestParseIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestParsePrefixedIntegerLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0xFF", 255},
+		{"0Xff", 255},
+		{"0o17", 15},
+		{"0b1010", 10},
+		{"1_000_000", 1000000},
+		{"0xFF_FF", 65535},
+		{"0b_1111_0000", 240},
+		//接頭辞のない数字は、0で始まっていても10進
+		{"0777", 777},
+		{"010", 10},
+		{"08", 8},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		parser := New(l)
+		program := parser.ParseProgram()
+		checkParsErrors(t, parser)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		integer, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("%q: stmt.Expression is not *ast.IntegerLiteral got=%T", tt.input, stmt.Expression)
+		}
+		if integer.Value != tt.expected {
+			t.Errorf("%q: integer.Value is not %d. got=%d", tt.input, tt.expected, integer.Value)
+		}
+		if integer.String() != tt.input {
+			t.Errorf("integer.String() wrong. expected=%q got=%q", tt.input, integer.String())
+		}
+	}
+}
+
+func TestParseBigIntegerLiteral(t *testing.T) {
+	input := "100000000000000000000;"
+
+	l := lexer.New(input)
+	parser := New(l)
+	program := parser.ParseProgram()
+	checkParsErrors(t, parser)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	integer, ok := stmt.Expression.(*ast.IntegerLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *ast.IntegerLiteral got=%T", stmt.Expression)
+	}
+	if integer.Big == nil || integer.Big.String() != "100000000000000000000" {
+		t.Errorf("integer.Big is not 100000000000000000000. got=%v", integer.Big)
+	}
+	if integer.String() != "100000000000000000000" {
+		t.Errorf("integer.String() wrong. got=%q", integer.String())
+	}
+}
+
+func TestParseFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14;", 3.14},
+		{"1e-9;", 1e-9},
+		{"2.5E+2;", 250},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		parser := New(l)
+		program := parser.ParseProgram()
+		checkParsErrors(t, parser)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statemsns, got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program statemens[0] is not ast.ExpressionStatement got=%T", program.Statements[0])
+		}
+
+		float, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("stmt.Expression is not *ast.FloatLiteral got=%T", stmt.Expression)
+		}
+		if float.Value != tt.expected {
+			t.Errorf("float.Value is not %g. got=%g", tt.expected, float.Value)
+		}
+	}
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+	input := `"hello world";`
+
+	l := lexer.New(input)
+	parser := New(l)
+	program := parser.ParseProgram()
+	checkParsErrors(t, parser)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.StringLiteral, got=%T", stmt.Expression)
+	}
+
+	if literal.Value != "hello world" {
+		t.Errorf("literal.Value not %q, got=%q", "hello world", literal.Value)
+	}
+}
+
 func TestParseBooleanExpresion(t *testing.T) {
 	input := "true;"
 
@@ -223,7 +336,7 @@ func TestParsePrefixExpressions(t *testing.T) {
 		}
 
 		if exp.Operator != tt.operator {
-			fmt.Errorf("exp.Operator is not %s, got=%s", exp.Operator, tt.operator)
+			t.Errorf("exp.Operator is not %s, got=%s", tt.operator, exp.Operator)
 		}
 		if !testLiteralExpresion(t, exp.Right, tt.integerValue) {
 			return
@@ -415,6 +528,46 @@ func TestFunctionLiteral(t *testing.T) {
 
 }
 
+func TestMacroLiteralParsing(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+	l := lexer.New(input)
+	parser := New(l)
+	program := parser.ParseProgram()
+	checkParsErrors(t, parser)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statemsns, got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program statemens[0] is not ast.ExpressionStatement got=%T", program.Statements[0])
+	}
+
+	macro, ok := stmt.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *ast.MacroLiteral got=%T", stmt.Expression)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("macro.Parameters does not contain 2 parameters, got=%d", len(macro.Parameters))
+	}
+
+	testIdentifierLiteral(t, macro.Parameters[0], "x")
+	testIdentifierLiteral(t, macro.Parameters[1], "y")
+
+	if len(macro.Body.Statements) != 1 {
+		t.Fatalf("macro.Body.Statements does not contain 1 statement, got=%d", len(macro.Body.Statements))
+	}
+
+	bodyStmt, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("macro body stmt is not *ast.ExpressionStatement got=%T", macro.Body.Statements[0])
+	}
+
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
 func TestFunctionParameters(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -490,6 +643,219 @@ func TestCallFunctionParsing(t *testing.T) {
 	testInfixExpression(t, callFunction.Arguments[2], 4, "+", 5)
 }
 
+func TestParsingArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	l := lexer.New(input)
+	parser := New(l)
+	program := parser.ParseProgram()
+	checkParsErrors(t, parser)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.ArrayLiteral, got=%T", stmt.Expression)
+	}
+
+	if len(array.Elements) != 3 {
+		t.Fatalf("len(array.Elements) not 3, got=%d", len(array.Elements))
+	}
+
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testInfixExpression(t, array.Elements[2], 3, "+", 3)
+}
+
+func TestParsingIndexExpressions(t *testing.T) {
+	input := "myArray[1 + 1]"
+
+	l := lexer.New(input)
+	parser := New(l)
+	program := parser.ParseProgram()
+	checkParsErrors(t, parser)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.IndexExpression, got=%T", stmt.Expression)
+	}
+
+	if !testIdentifierLiteral(t, indexExp.Left, "myArray") {
+		return
+	}
+
+	testInfixExpression(t, indexExp.Index, 1, "+", 1)
+}
+
+func TestParsingHashLiterals(t *testing.T) {
+	input := `{"one": 1, "two": 2, 3: 0 + 3, true: "t"}`
+
+	l := lexer.New(input)
+	parser := New(l)
+	program := parser.ParseProgram()
+	checkParsErrors(t, parser)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.HashLiteral, got=%T", stmt.Expression)
+	}
+
+	if len(hash.Pairs) != 4 {
+		t.Fatalf("hash.Pairs has wrong length, got=%d", len(hash.Pairs))
+	}
+
+	if key, ok := hash.Pairs[0].Key.(*ast.StringLiteral); !ok || key.Value != "one" {
+		t.Errorf("hash.Pairs[0].Key not \"one\", got=%s", hash.Pairs[0].Key)
+	}
+	testIntegerLiteral(t, hash.Pairs[0].Value, 1)
+
+	if key, ok := hash.Pairs[1].Key.(*ast.StringLiteral); !ok || key.Value != "two" {
+		t.Errorf("hash.Pairs[1].Key not \"two\", got=%s", hash.Pairs[1].Key)
+	}
+	testIntegerLiteral(t, hash.Pairs[1].Value, 2)
+
+	testIntegerLiteral(t, hash.Pairs[2].Key, 3)
+	testInfixExpression(t, hash.Pairs[2].Value, 0, "+", 3)
+
+	testBooleanExpression(t, hash.Pairs[3].Key, true)
+}
+
+func TestParsingEmptyHashLiteral(t *testing.T) {
+	l := lexer.New("{}")
+	parser := New(l)
+	program := parser.ParseProgram()
+	checkParsErrors(t, parser)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.HashLiteral, got=%T", stmt.Expression)
+	}
+
+	if len(hash.Pairs) != 0 {
+		t.Errorf("hash.Pairs has wrong length, got=%d", len(hash.Pairs))
+	}
+}
+
+func TestParsingHashLiteralInBlock(t *testing.T) {
+	input := `if (x) { {"a": 1} } else { let h = {}; h }`
+
+	l := lexer.New(input)
+	parser := New(l)
+	program := parser.ParseProgram()
+	checkParsErrors(t, parser)
+
+	exp := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+	consequence := exp.Consequence.Statements[0].(*ast.ExpressionStatement)
+	if _, ok := consequence.Expression.(*ast.HashLiteral); !ok {
+		t.Errorf("consequence not *ast.HashLiteral, got=%T", consequence.Expression)
+	}
+
+	let := exp.Alternative.Statements[0].(*ast.LetStatementNode)
+	if _, ok := let.Value.(*ast.HashLiteral); !ok {
+		t.Errorf("let value not *ast.HashLiteral, got=%T", let.Value)
+	}
+}
+
+func TestWhileStatement(t *testing.T) {
+	input := "while (x < 10) { break; continue; x }"
+	l := lexer.New(input)
+	parser := New(l)
+	program := parser.ParseProgram()
+	checkParsErrors(t, parser)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statemsns, got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("program statemens[0] is not ast.WhileStatement got=%T", program.Statements[0])
+	}
+
+	if !testInfixExpression(t, stmt.Condition, "x", "<", 10) {
+		return
+	}
+
+	if len(stmt.Body.Statements) != 3 {
+		t.Fatalf("body does not contain 3 statements, got=%d", len(stmt.Body.Statements))
+	}
+	if _, ok := stmt.Body.Statements[0].(*ast.BreakStatement); !ok {
+		t.Errorf("body.Statements[0] is not ast.BreakStatement got=%T", stmt.Body.Statements[0])
+	}
+	if _, ok := stmt.Body.Statements[1].(*ast.ContinueStatement); !ok {
+		t.Errorf("body.Statements[1] is not ast.ContinueStatement got=%T", stmt.Body.Statements[1])
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	input := "for (x in [1, 2]) { if (x > 1) { break } }"
+	l := lexer.New(input)
+	parser := New(l)
+	program := parser.ParseProgram()
+	checkParsErrors(t, parser)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statemsns, got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ForStatement)
+	if !ok {
+		t.Fatalf("program statemens[0] is not ast.ForStatement got=%T", program.Statements[0])
+	}
+
+	if !testIdentifierLiteral(t, stmt.Variable, "x") {
+		return
+	}
+	if stmt.Iterable.String() != "[1, 2]" {
+		t.Errorf("stmt.Iterable is not [1, 2]. got=%q", stmt.Iterable.String())
+	}
+	if len(stmt.Body.Statements) != 1 {
+		t.Fatalf("body does not contain 1 statement, got=%d", len(stmt.Body.Statements))
+	}
+	if stmt.String() != "for(x in [1, 2]) if(x > 1) break;" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestLoopStatementsWithSemicolon(t *testing.T) {
+	input := "while (c) { x }; for (y in z) { y }; s"
+	l := lexer.New(input)
+	parser := New(l)
+	program := parser.ParseProgram()
+	checkParsErrors(t, parser)
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statemsns, got=%d", len(program.Statements))
+	}
+	if _, ok := program.Statements[0].(*ast.WhileStatement); !ok {
+		t.Errorf("program statemens[0] is not ast.WhileStatement got=%T", program.Statements[0])
+	}
+	if _, ok := program.Statements[1].(*ast.ForStatement); !ok {
+		t.Errorf("program statemens[1] is not ast.ForStatement got=%T", program.Statements[1])
+	}
+	if program.Statements[2].String() != "s" {
+		t.Errorf("program statemens[2] wrong. got=%q", program.Statements[2].String())
+	}
+}
+
+func TestSkipComments(t *testing.T) {
+	input := `// 先頭のコメント
+let x = /* 値 */ 1; // 行末のコメント
+/* /* 入れ子 */ */ x + 2`
+
+	for _, l := range []lexer.Lexer{lexer.New(input), lexer.NewWithComments(input)} {
+		parser := New(l)
+		program := parser.ParseProgram()
+		checkParsErrors(t, parser)
+
+		if program.String() != "let x = 1;(x + 2)" {
+			t.Errorf("expected=%q, got=%q", "let x = 1;(x + 2)", program.String())
+		}
+	}
+}
+
 func TestOperatorPrecedencesParsing(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -583,6 +949,74 @@ func TestOperatorPrecedencesParsing(t *testing.T) {
 			input:    "add(a + b + c * d /f + g)",
 			expected: "add((((a + b) + ((c * d) / f)) + g))",
 		},
+		{
+			input:    "a * [1, 2, 3, 4][b * c] * d",
+			expected: "((a * ([1, 2, 3, 4][(b * c)])) * d)",
+		},
+		{
+			input:    "add(a * b[2], b[1], 2 * [1, 2][1])",
+			expected: "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
+		},
+		{
+			input:    "x = 1 + 2",
+			expected: "(x = (1 + 2))",
+		},
+		{
+			input:    "a = b = c",
+			expected: "(a = (b = c))",
+		},
+		{
+			input:    "x += y * 2",
+			expected: "(x += (y * 2))",
+		},
+		{
+			input:    "a[i + 1] -= 1 == 2",
+			expected: "((a[(i + 1)]) -= (1 == 2))",
+		},
+		{
+			input:    "x /= y *= 2",
+			expected: "(x /= (y *= 2))",
+		},
+		{
+			input:    "a <= b == b >= c",
+			expected: "((a <= b) == (b >= c))",
+		},
+		{
+			input:    "a || b && c || d",
+			expected: "((a || (b && c)) || d)",
+		},
+		{
+			input:    "a == b && c != d",
+			expected: "((a == b) && (c != d))",
+		},
+		{
+			input:    "!a && b",
+			expected: "((!a) && b)",
+		},
+		{
+			input:    "a + b % c",
+			expected: "(a + (b % c))",
+		},
+		{
+			input:    "a | b & c ^ d",
+			expected: "((a | (b & c)) ^ d)",
+		},
+		{
+			input:    "a << b + c >> d",
+			expected: "((a << b) + (c >> d))",
+		},
+		{
+			input:    "~a & -b",
+			expected: "((~a) & (-b))",
+		},
+		{
+			input:    "a | b == c",
+			expected: "((a | b) == c)",
+		},
+		{
+			input:    "x = a || b",
+			expected: "(x = (a || b))",
+		},
 	}
 
 	for _, tt := range tests {
@@ -598,6 +1032,309 @@ func TestOperatorPrecedencesParsing(t *testing.T) {
 	}
 }
 
+func TestParserErrorPositions(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{
+			input:         "let = 5;",
+			expectedError: "1:5: expected IDENT",
+		},
+		{
+			input:         "let x = 1;\nlet y 2;",
+			expectedError: "2:7: expected =",
+		},
+		{
+			input:         "let f = fn(x) {\n  x\n}\nf(1, 2",
+			expectedError: "4:7: expected )",
+		},
+		{
+			input:         "\n  ) + 1",
+			expectedError: "2:3: no prefix parse function for ) found",
+		},
+		{
+			input:         "1 + x = 2",
+			expectedError: "1:1: invalid assignment target: (1 + x)",
+		},
+		{
+			input:         "f() += 1",
+			expectedError: "1:1: invalid assignment target: f()",
+		},
+		{
+			input:         "let x = 1;\nbreak;",
+			expectedError: "2:1: break outside loop",
+		},
+		{
+			input:         "while (true) { let f = fn() { continue; }; }",
+			expectedError: "1:31: continue outside loop",
+		},
+		{
+			input:         "for (1 in x) { }",
+			expectedError: "1:6: expected IDENT",
+		},
+		{
+			input:         "for (x of y) { }",
+			expectedError: "1:8: expected IN",
+		},
+		{
+			input:         "let mask = 0x;",
+			expectedError: "1:12: could not parse \"0x\" as integer",
+		},
+		{
+			input:         "let n = 1 +\n  1__0;",
+			expectedError: "2:3: could not parse \"1__0\" as integer",
+		},
+		{
+			input:         "0b102",
+			expectedError: "1:1: could not parse \"0b102\" as integer",
+		},
+		{
+			input:         "1_000_",
+			expectedError: "1:1: could not parse \"1_000_\" as integer",
+		},
+		{
+			input:         "1__0.5",
+			expectedError: "1:1: could not parse \"1__0.5\" as float",
+		},
+		{
+			input:         "let x = 1.5e;",
+			expectedError: "1:9: malformed float literal \"1.5e\"",
+		},
+		{
+			input:         "let x = 2 *\n  1.e5",
+			expectedError: "2:3: malformed float literal \"1.e5\"",
+		},
+		{
+			input:         "[1e+, 2]",
+			expectedError: "1:2: malformed float literal \"1e+\"",
+		},
+		{
+			input:         "let 名前 = ;",
+			expectedError: "1:10: no prefix parse function for ; found",
+		},
+		{
+			input:         "let s = \"abc",
+			expectedError: "1:9: unterminated string literal",
+		},
+		{
+			input:         `"abc\`,
+			expectedError: "1:1: unterminated string literal",
+		},
+		{
+			input:         `"\q`,
+			expectedError: "1:1: unterminated string literal",
+		},
+		{
+			input:         `"\q"`,
+			expectedError: `1:1: invalid escape sequence \q`,
+		},
+		{
+			input:         "let s = 1;\n  \"\\u{110000}\"",
+			expectedError: `2:3: invalid escape sequence \u{110000}`,
+		},
+		{
+			input:         `puts("ok", "\u{D800}")`,
+			expectedError: `1:12: invalid escape sequence \u{D800}`,
+		},
+		{
+			input:         `"\u0041"`,
+			expectedError: `1:1: invalid escape sequence \u`,
+		},
+		{
+			input:         "let x = 1; /* a",
+			expectedError: "1:12: unterminated block comment",
+		},
+		{
+			input:         "let x = 1;\n  /* a /* b */\nx",
+			expectedError: "2:3: unterminated block comment",
+		},
+		{
+			input:         "let f = fn(x) { x",
+			expectedError: "1:18: expected }",
+		},
+		{
+			input:         "if (true) { 1",
+			expectedError: "1:14: expected }",
+		},
+		{
+			input:         "while (x) {\n  x = x - 1;\n",
+			expectedError: "3:1: expected }",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		parser := New(l)
+		parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parse errors for %q", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestParserErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors []string
+		statements     int
+	}{
+		{
+			input:          "let = 5; let y = 10; let 838383;",
+			expectedErrors: []string{"1:5: expected IDENT", "1:26: expected IDENT"},
+			statements:     1,
+		},
+		{
+			input: `let a = 1;
+let b = (1 + ;
+let c = 3;
+let d = fn(x { x };
+let e = add(1, 2;
+e;`,
+			expectedErrors: []string{"2:14: no prefix parse function for ; found", "4:14: expected )", "5:17: expected )"},
+			statements:     3,
+		},
+		{
+			input:          `let f = fn(x) { let = 1; x }; f(1)`,
+			expectedErrors: []string{"1:21: expected IDENT"},
+			statements:     2,
+		},
+		{
+			input:          `if (x { 1 }; let y = {"a" 1, "b": 2}; y`,
+			expectedErrors: []string{"1:7: expected )", "1:27: expected :"},
+			statements:     1,
+		},
+		{
+			input:          `if (x) { 1 + }; 2`,
+			expectedErrors: []string{"1:14: no prefix parse function for } found"},
+			statements:     2,
+		},
+		{
+			input:          `fn(1, y) { y }`,
+			expectedErrors: []string{"1:4: expected IDENT"},
+			statements:     0,
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		parser := New(l)
+		program := parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("wrong number of errors for %q. expected=%q, got=%q", tt.input, tt.expectedErrors, errors)
+			continue
+		}
+		for i, expected := range tt.expectedErrors {
+			if errors[i] != expected {
+				t.Errorf("errors[%d] wrong. expected=%q, got=%q", i, expected, errors[i])
+			}
+		}
+
+		if len(program.Statements) != tt.statements {
+			t.Errorf("wrong number of statements for %q. expected=%d, got=%d", tt.input, tt.statements, len(program.Statements))
+		}
+	}
+}
+
+func TestParseErrorFields(t *testing.T) {
+	l := lexer.New("let x 5;")
+	parser := New(l)
+	parser.ParseProgram()
+
+	errors := parser.ParseErrors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error, got=%d", len(errors))
+	}
+
+	err := errors[0]
+	if err.Pos.String() != "1:7" {
+		t.Errorf("err.Pos wrong. got=%s", err.Pos)
+	}
+	if err.Expected != token.ASSIGN {
+		t.Errorf("err.Expected wrong. got=%q", err.Expected)
+	}
+	if err.Found.Type != token.INT || err.Found.Literal != "5" {
+		t.Errorf("err.Found wrong. got=%+v", err.Found)
+	}
+	if err.Message != "expected =" {
+		t.Errorf("err.Message wrong. got=%q", err.Message)
+	}
+}
+
+func TestMaxErrors(t *testing.T) {
+	input := ""
+	for i := 0; i < MaxErrors*2; i++ {
+		input += "let = 1;\n"
+	}
+
+	l := lexer.New(input)
+	parser := New(l)
+	parser.ParseProgram()
+
+	errors := parser.Errors()
+	if len(errors) != MaxErrors {
+		t.Fatalf("expected %d errors, got=%d", MaxErrors, len(errors))
+	}
+	if errors[MaxErrors-2] != fmt.Sprintf("%d:5: expected IDENT", MaxErrors-1) {
+		t.Errorf("wrong error. got=%q", errors[MaxErrors-2])
+	}
+	if errors[MaxErrors-1] != fmt.Sprintf("%d:5: too many errors", MaxErrors) {
+		t.Errorf("wrong last error. got=%q", errors[MaxErrors-1])
+	}
+}
+
+func TestNodePositions(t *testing.T) {
+	input := `let add = fn(a, b) {
+  a + b;
+};
+add(1, [2][0]);
+{"k": -x}`
+
+	l := lexer.New(input)
+	parser := New(l)
+	program := parser.ParseProgram()
+	checkParsErrors(t, parser)
+
+	let := program.Statements[0].(*ast.LetStatementNode)
+	fn := let.Value.(*ast.FunctionLiteral)
+	call := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	index := call.Arguments[1].(*ast.IndexExpression)
+	hash := program.Statements[2].(*ast.ExpressionStatement).Expression.(*ast.HashLiteral)
+
+	tests := []struct {
+		node        ast.Node
+		expectedPos string
+		expectedEnd string
+	}{
+		{let, "1:1", "3:2"},
+		{fn, "1:11", "3:2"},
+		{fn.Body, "1:20", "3:2"},
+		{fn.Body.Statements[0], "2:3", "2:8"},
+		{call, "4:1", "4:15"},
+		{index, "4:8", "4:14"},
+		{hash, "5:1", "5:10"},
+		{hash.Pairs[0].Value, "5:7", "5:9"},
+		{program, "1:1", "5:10"},
+	}
+
+	for i, tt := range tests {
+		if tt.node.Pos().String() != tt.expectedPos {
+			t.Errorf("tests[%d] - Pos wrong expected=%s got=%s", i, tt.expectedPos, tt.node.Pos())
+		}
+		if tt.node.End().String() != tt.expectedEnd {
+			t.Errorf("tests[%d] - End wrong expected=%s got=%s", i, tt.expectedEnd, tt.node.End())
+		}
+	}
+}
+
 func checkParsErrors(t *testing.T, parse *Parser) {
 	errors := parse.Errors()
 
@@ -0,0 +1,161 @@
+package repl
+
+import (
+	"fmt"
+	"interpreter-go/lexer"
+	"interpreter-go/parser"
+	"interpreter-go/token"
+	"io/ioutil"
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+//":"で始まる行は、Monkeyのコードではなくメタコマンドとして扱う
+type command struct {
+	name  string
+	args  string //引数の説明。引数を取らなければ空
+	usage string
+	run   func(s *session, arg string)
+}
+
+var commands []command
+
+//commandsの初期化でhelpがcommandsを参照するので、initで設定する
+func init() {
+	commands = []command{
+		{"env", "", "list the bindings of the session", (*session).listBindings},
+		{"reset", "", "discard all bindings and macros", func(s *session, _ string) { s.reset() }},
+		{"tokens", "<code>", "print the tokens of code", (*session).printTokens},
+		{"ast", "<code>", "print the syntax tree of code", (*session).printAST},
+		{"load", "<file>", "run a file in the session", (*session).load},
+		{"help", "", "show this help", (*session).help},
+	}
+}
+
+func isCommand(input string) bool {
+	return strings.HasPrefix(strings.TrimSpace(input), ":")
+}
+
+func (s *session) runCommand(input string) {
+	input = strings.TrimSpace(input)
+	name, arg := input[1:], ""
+	if i := strings.IndexAny(name, " \t"); i >= 0 {
+		name, arg = name[:i], strings.TrimSpace(name[i+1:])
+	}
+
+	for _, c := range commands {
+		if c.name == name {
+			c.run(s, arg)
+			return
+		}
+	}
+	fmt.Fprintf(s.out, "unknown command: :%s (type :help for a list of commands)\n", name)
+}
+
+func (s *session) help(string) {
+	for _, c := range commands {
+		usage := ":" + c.name
+		if c.args != "" {
+			usage += " " + c.args
+		}
+		fmt.Fprintf(s.out, "%-15s %s\n", usage, c.usage)
+	}
+}
+
+func (s *session) listBindings(string) {
+	for _, name := range s.env.Names() {
+		value, _ := s.env.Get(name)
+		fmt.Fprintf(s.out, "%s = %s\n", name, value.Inspect())
+	}
+	for _, name := range s.macroEnv.Names() {
+		fmt.Fprintf(s.out, "%s = macro\n", name)
+	}
+}
+
+func (s *session) printTokens(code string) {
+	l := lexer.New(code)
+	for {
+		tok := l.NextToken()
+		if tok.Type == token.EOF {
+			return
+		}
+		fmt.Fprintf(s.out, "%s\t%s\t%q\n", tok.Pos, tok.Type, tok.Literal)
+	}
+}
+
+func (s *session) printAST(code string) {
+	p := parser.New(lexer.New(code))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParseErrors(s.out, p.Errors())
+		return
+	}
+	for _, stmt := range program.Statements {
+		s.writeTree(stmt, "", 0)
+	}
+}
+
+var (
+	tokenType = reflect.TypeOf(token.Token{})
+	bigType   = reflect.TypeOf(&big.Int{})
+)
+
+//ノードの型と値を1行に書き、子のノードを字下げして続ける。
+//フィールドをリフレクションでたどるので、ノードの種類を増やしてもそのまま表示できる
+func (s *session) writeTree(node interface{}, label string, depth int) {
+	prefix := strings.Repeat("  ", depth)
+	if label != "" {
+		prefix += label + ": "
+	}
+	v := reflect.ValueOf(node)
+	if !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		fmt.Fprintf(s.out, "%snil\n", prefix)
+		return
+	}
+	v = reflect.Indirect(v)
+
+	line := prefix + v.Type().Name()
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		switch {
+		case field.Type == bigType:
+			if !value.IsNil() {
+				line += fmt.Sprintf(" %s=%s", field.Name, value.Interface())
+			}
+		case field.Type.Kind() == reflect.String:
+			if value.String() != "" {
+				line += fmt.Sprintf(" %s=%q", field.Name, value.String())
+			}
+		case field.Type.Kind() == reflect.Int64, field.Type.Kind() == reflect.Float64, field.Type.Kind() == reflect.Bool:
+			line += fmt.Sprintf(" %s=%v", field.Name, value.Interface())
+		}
+	}
+	fmt.Fprintln(s.out, line)
+
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		switch {
+		case field.Type == tokenType || field.Type == bigType:
+		case field.Type.Kind() == reflect.Interface, field.Type.Kind() == reflect.Ptr:
+			s.writeTree(value.Interface(), field.Name, depth+1)
+		case field.Type.Kind() == reflect.Slice:
+			for j := 0; j < value.Len(); j++ {
+				s.writeTree(value.Index(j).Interface(), fmt.Sprintf("%s[%d]", field.Name, j), depth+1)
+			}
+		}
+	}
+}
+
+func (s *session) load(path string) {
+	if path == "" {
+		fmt.Fprintln(s.out, "usage: :load <file>")
+		return
+	}
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(s.out, "%s\n", err)
+		return
+	}
+	s.eval(lexer.StripShebang(string(source)))
+}
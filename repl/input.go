@@ -0,0 +1,124 @@
+package repl
+
+import (
+	"bufio"
+	"interpreter-go/lexer"
+	"interpreter-go/token"
+	"io"
+	"strings"
+)
+
+//入力が続くときのプロンプト
+const CONTINUATION_PROMPT = ".. "
+
+//1行ずつ読む。端末ならlineEditor、それ以外はbufio.Scannerを使う
+type lineReader interface {
+	readLine(prompt string) (string, error)
+}
+
+type scannerReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (r *scannerReader) readLine(prompt string) (string, error) {
+	io.WriteString(r.out, prompt)
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+//1つの文が完成するまで行を読み、改行でつないで返す。メタコマンドは1行で終わる。
+//途中で入力が終わったときは、それまでの入力をそのまま返す。Ctrl-Cで取り消したときは空の入力を返す
+func readInput(r lineReader) (string, bool) {
+	input, err := r.readLine(PROMPT)
+	if err == errInterrupted {
+		return "", true
+	}
+	if err != nil {
+		return "", false
+	}
+
+	for !isCommand(input) && isIncomplete(input) {
+		line, err := r.readLine(CONTINUATION_PROMPT)
+		if err == errInterrupted {
+			return "", true
+		}
+		if err != nil {
+			break
+		}
+		input += "\n" + line
+	}
+	return input, true
+}
+
+//行末で続きが必要になるトークン
+var continuationTokens = map[token.TokenType]bool{
+	token.ASSIGN:          true,
+	token.PLUS_ASSIGN:     true,
+	token.MINUS_ASSIGN:    true,
+	token.ASTERISK_ASSIGN: true,
+	token.SLASH_ASSIGN:    true,
+	token.PLUS:            true,
+	token.MINUS:           true,
+	token.SLASH:           true,
+	token.ASTERISK:        true,
+	token.PERCENT:         true,
+	token.LT:              true,
+	token.RT:              true,
+	token.LT_EQ:           true,
+	token.RT_EQ:           true,
+	token.BANG:            true,
+	token.EQ:              true,
+	token.NOT_EQ:          true,
+	token.AND:             true,
+	token.OR:              true,
+	token.BIT_AND:         true,
+	token.BIT_OR:          true,
+	token.BIT_XOR:         true,
+	token.BIT_NOT:         true,
+	token.SHIFT_LEFT:      true,
+	token.SHIFT_RIGHT:     true,
+	token.COMMA:           true,
+	token.COLON:           true,
+	token.ELSE:            true,
+}
+
+//括弧が閉じていない、演算子で終わっている、文字列やコメントが閉じていない場合はtrue。
+//閉じ括弧が多すぎるなど、続けても直らない誤りはパーサーに報告させる
+func isIncomplete(input string) bool {
+	l := lexer.New(input)
+	depth := 0
+	var last token.Token
+	for {
+		tok := l.NextToken()
+		switch tok.Type {
+		case token.EOF:
+			return depth > 0 || continuationTokens[last.Type]
+		case token.LPAREN, token.LBRACE, token.LBRACKET:
+			depth++
+		case token.RPAREN, token.RBRACE, token.RBRACKET:
+			depth--
+			if depth < 0 {
+				return false
+			}
+		case token.ILEEGAL:
+			if isUnterminated(tok, input) {
+				return true
+			}
+		}
+		last = tok
+	}
+}
+
+//閉じられていない文字列とブロックコメントは、入力の終わりまでをリテラルにしたILEEGALになる
+func isUnterminated(tok token.Token, input string) bool {
+	if !strings.HasPrefix(tok.Literal, `"`) && !strings.HasPrefix(tok.Literal, "/*") {
+		return false
+	}
+	return tok.Pos.Offset+len(tok.Literal) == len(input)
+}
@@ -0,0 +1,387 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//Ctrl-Cで入力を取り消した
+var errInterrupted = errors.New("interrupted")
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyBackspace = 8
+	keyTab       = 9
+	keyLF        = 10
+	keyCtrlK     = 11
+	keyCR        = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+
+	//エスケープシーケンスは、制御文字と重ならない負の値にする
+	keyUp = -(iota + 1)
+	keyDown
+	keyRight
+	keyLeft
+	keyHome
+	keyEnd
+	keyDeleteForward
+	keyUnknown
+)
+
+//端末で1行を編集する。端末をrawモードにするのは呼び出し側の役目で、
+//ここではキー入力の解釈と画面の書き換えだけを行う
+type lineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	history  []string
+	complete func(prefix string) []string //prefixで始まる補完候補を返す
+
+	prompt string
+	line   []rune
+	pos    int //カーソルの位置。lineの添字
+}
+
+func newLineEditor(in io.Reader, out io.Writer, history []string, complete func(string) []string) *lineEditor {
+	return &lineEditor{in: bufio.NewReader(in), out: out, history: history, complete: complete}
+}
+
+//Enterで確定した行を返す。空の行でCtrl-Dを押すとio.EOF、Ctrl-CでerrInterruptedを返す
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	e.prompt, e.line, e.pos = prompt, nil, 0
+	historyIndex := len(e.history)
+	editing := "" //履歴をさかのぼる前に入力していた行
+	e.refresh()
+
+	for {
+		key, r, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+
+		switch key {
+		case keyCR, keyLF:
+			line := string(e.line)
+			io.WriteString(e.out, "\r\n")
+			e.addHistory(line)
+			return line, nil
+		case keyCtrlC:
+			io.WriteString(e.out, "^C\r\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(e.line) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			e.deleteAt(e.pos)
+		case keyDeleteForward:
+			e.deleteAt(e.pos)
+		case keyBackspace, keyDelete:
+			if e.pos > 0 {
+				e.pos--
+				e.deleteAt(e.pos)
+			}
+		case keyCtrlA, keyHome:
+			e.pos = 0
+		case keyCtrlE, keyEnd:
+			e.pos = len(e.line)
+		case keyCtrlB, keyLeft:
+			if e.pos > 0 {
+				e.pos--
+			}
+		case keyCtrlF, keyRight:
+			if e.pos < len(e.line) {
+				e.pos++
+			}
+		case keyCtrlK:
+			e.line = e.line[:e.pos]
+		case keyCtrlU:
+			e.line = e.line[e.pos:]
+			e.pos = 0
+		case keyCtrlP, keyUp, keyCtrlN, keyDown:
+			if historyIndex == len(e.history) {
+				editing = string(e.line)
+			}
+			if key == keyCtrlP || key == keyUp {
+				if historyIndex == 0 {
+					continue
+				}
+				historyIndex--
+			} else {
+				if historyIndex == len(e.history) {
+					continue
+				}
+				historyIndex++
+			}
+			if historyIndex == len(e.history) {
+				e.setLine(editing)
+			} else {
+				e.setLine(e.history[historyIndex])
+			}
+		case keyTab:
+			e.completeWord()
+		case keyCtrlR:
+			line, submitted, err := e.reverseSearch()
+			if err != nil {
+				return "", err
+			}
+			if submitted {
+				io.WriteString(e.out, "\r\n")
+				e.addHistory(line)
+				return line, nil
+			}
+		case 0:
+			if unicode.IsPrint(r) {
+				e.insert(r)
+			}
+		}
+		e.refresh()
+	}
+}
+
+//制御文字とエスケープシーケンスはkeyで、それ以外の文字はkeyを0にしてrで返す
+func (e *lineEditor) readKey() (int, rune, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil {
+		return 0, 0, err
+	}
+	if r != keyEscape {
+		if r < 32 || r == keyDelete {
+			return int(r), 0, nil
+		}
+		return 0, r, nil
+	}
+
+	//ESC [ A や ESC O H の形式。ESC [ 3 ~ のように数字と'~'が続くものもある
+	next, _, err := e.in.ReadRune()
+	if err != nil {
+		return 0, 0, err
+	}
+	if next != '[' && next != 'O' {
+		return keyUnknown, 0, nil
+	}
+	code, _, err := e.in.ReadRune()
+	if err != nil {
+		return 0, 0, err
+	}
+	digits := ""
+	for unicode.IsDigit(code) || code == ';' {
+		digits += string(code)
+		if code, _, err = e.in.ReadRune(); err != nil {
+			return 0, 0, err
+		}
+	}
+	switch {
+	case code == 'A':
+		return keyUp, 0, nil
+	case code == 'B':
+		return keyDown, 0, nil
+	case code == 'C':
+		return keyRight, 0, nil
+	case code == 'D':
+		return keyLeft, 0, nil
+	case code == 'H', code == '~' && (digits == "1" || digits == "7"):
+		return keyHome, 0, nil
+	case code == 'F', code == '~' && (digits == "4" || digits == "8"):
+		return keyEnd, 0, nil
+	case code == '~' && digits == "3":
+		return keyDeleteForward, 0, nil
+	default:
+		return keyUnknown, 0, nil
+	}
+}
+
+func (e *lineEditor) insert(r rune) {
+	e.line = append(e.line, 0)
+	copy(e.line[e.pos+1:], e.line[e.pos:])
+	e.line[e.pos] = r
+	e.pos++
+}
+
+func (e *lineEditor) deleteAt(i int) {
+	if i < len(e.line) {
+		e.line = append(e.line[:i], e.line[i+1:]...)
+	}
+}
+
+func (e *lineEditor) setLine(line string) {
+	e.line = []rune(line)
+	e.pos = len(e.line)
+}
+
+//空の行と、直前と同じ行は履歴に残さない
+func (e *lineEditor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if n := len(e.history); n > 0 && e.history[n-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+}
+
+//カーソルの前の識別子を補完する。候補が1つならそのまま、複数なら共通部分まで補完し、
+//それ以上補完できなければ候補を一覧にする
+func (e *lineEditor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+	start := e.pos
+	for start > 0 && isIdentRune(e.line[start-1]) {
+		start--
+	}
+	prefix := string(e.line[start:e.pos])
+	if prefix == "" {
+		return
+	}
+
+	candidates := e.complete(prefix)
+	if len(candidates) == 0 {
+		return
+	}
+	common := []rune(commonPrefix(candidates))
+	if len(common) > len([]rune(prefix)) {
+		for _, r := range common[len([]rune(prefix)):] {
+			e.insert(r)
+		}
+		return
+	}
+	if len(candidates) > 1 {
+		io.WriteString(e.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+	}
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+func commonPrefix(words []string) string {
+	prefix := []rune(words[0])
+	for _, w := range words[1:] {
+		runes := []rune(w)
+		i := 0
+		for i < len(prefix) && i < len(runes) && prefix[i] == runes[i] {
+			i++
+		}
+		prefix = prefix[:i]
+	}
+	return string(prefix)
+}
+
+//Ctrl-Rで履歴を新しい方からさかのぼって、入力した文字列を含む行を探す。
+//もう一度Ctrl-Rを押すとさらに古い行を探す。Enterで見つけた行を確定し、
+//Ctrl-GかCtrl-Cで元の行に戻る。それ以外のキーでは見つけた行の編集に戻る
+func (e *lineEditor) reverseSearch() (string, bool, error) {
+	original, originalPos := e.line, e.pos
+	query := []rune{}
+	index := len(e.history)
+	match := ""
+
+	search := func(from int) {
+		for i := from; i >= 0; i-- {
+			if strings.Contains(e.history[i], string(query)) {
+				index, match = i, e.history[i]
+				return
+			}
+		}
+	}
+
+	for {
+		fmt.Fprintf(e.out, "\r(reverse-i-search)`%s': %s\x1b[K", string(query), match)
+
+		key, r, err := e.readKey()
+		if err != nil {
+			return "", false, err
+		}
+		switch key {
+		case keyCtrlR:
+			search(index - 1)
+		case keyBackspace, keyDelete:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				index, match = len(e.history), ""
+				search(index - 1)
+			}
+		case keyCR, keyLF:
+			return match, true, nil
+		case keyCtrlG, keyCtrlC:
+			e.line, e.pos = original, originalPos
+			return "", false, nil
+		case 0:
+			query = append(query, r)
+			if index < len(e.history) {
+				search(index)
+			} else {
+				search(index - 1)
+			}
+		default:
+			e.setLine(match)
+			return "", false, nil
+		}
+	}
+}
+
+//行を書き直して、カーソルを表示上の位置に移す
+func (e *lineEditor) refresh() {
+	io.WriteString(e.out, "\r"+e.prompt+string(e.line)+"\x1b[K")
+	column := displayWidth(e.prompt) + displayWidth(string(e.line[:e.pos]))
+	io.WriteString(e.out, "\r")
+	if column > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dC", column)
+	}
+}
+
+//全角文字は端末上で2桁を使う
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		if isWide(r) {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+func isWide(r rune) bool {
+	return r >= 0x1100 && (r <= 0x115f || //ハングル字母
+		(r >= 0x2e80 && r <= 0xa4cf && r != 0x303f) || //CJK、ひらがな、カタカナ
+		(r >= 0xac00 && r <= 0xd7a3) || //ハングル
+		(r >= 0xf900 && r <= 0xfaff) || //CJK互換漢字
+		(r >= 0xfe30 && r <= 0xfe4f) ||
+		(r >= 0xff00 && r <= 0xff60) || //全角英数
+		(r >= 0xffe0 && r <= 0xffe6) ||
+		(r >= 0x20000 && r <= 0x3fffd))
+}
+
+//namesのうちprefixで始まるものを、重複なしで辞書順に返す
+func sortedCandidates(prefix string, names ...[]string) []string {
+	seen := map[string]bool{}
+	candidates := []string{}
+	for _, list := range names {
+		for _, name := range list {
+			if strings.HasPrefix(name, prefix) && !seen[name] {
+				seen[name] = true
+				candidates = append(candidates, name)
+			}
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
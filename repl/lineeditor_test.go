@@ -0,0 +1,198 @@
+package repl
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	up    = "\x1b[A"
+	down  = "\x1b[B"
+	right = "\x1b[C"
+	left  = "\x1b[D"
+	home  = "\x1b[H"
+	del   = "\x1b[3~"
+)
+
+func TestLineEditorReadLine(t *testing.T) {
+	complete := func(prefix string) []string {
+		return sortedCandidates(prefix, []string{"let", "len", "fn", "first", "firstName"})
+	}
+
+	tests := []struct {
+		name     string
+		history  []string
+		keys     string
+		expected []string
+	}{
+		{"typing", nil, "let x = 1;\r", []string{"let x = 1;"}},
+		{"line feed", nil, "x\n", []string{"x"}},
+		{"multibyte", nil, "\"名前\"\r", []string{"\"名前\""}},
+		{"backspace", nil, "abx\x7fc\r", []string{"abc"}},
+		{"cursor", nil, "ac" + left + "b" + right + "d\r", []string{"abcd"}},
+		{"home end", nil, "bc" + home + "a\x05d\r", []string{"abcd"}},
+		{"delete forward", nil, "abc" + home + del + "\x04\r", []string{"c"}},
+		{"kill", nil, "abcd\x02\x02\x0b\r", []string{"ab"}},
+		{"kill before", nil, "abcd\x02\x15\r", []string{"d"}},
+		{"control ignored", nil, "a\x0fb\r", []string{"ab"}},
+		{"history", []string{"first", "second"}, up + "\r" + up + up + up + "\r", []string{"second", "first"}},
+		{"history down", []string{"first", "second"}, "new" + up + up + down + down + "\r", []string{"new"}},
+		{"history ctrl", []string{"first", "second"}, "\x10\x10\x0e\r", []string{"second"}},
+		{"history skips duplicates", nil, "a\ra\r" + up + up + "\r", []string{"a", "a", "a"}},
+		{"complete single", nil, "firstN\t\r", []string{"firstName"}},
+		{"complete unique", nil, "le\x02\x06t\t = 1\r", []string{"let = 1"}},
+		{"complete common prefix", nil, "fi\t\r", []string{"first"}},
+		{"complete after operator", nil, "1+fir\tN\t\r", []string{"1+firstName"}},
+		{"reverse search", []string{"let a = 1;", "puts(a)", "let b = 2;"}, "\x12let\r", []string{"let b = 2;"}},
+		{"reverse search older", []string{"let a = 1;", "puts(a)", "let b = 2;"}, "\x12let\x12\r", []string{"let a = 1;"}},
+		{"reverse search edit", []string{"puts(a)"}, "\x12put\x05 + 1\r", []string{"puts(a) + 1"}},
+		{"reverse search cancel", []string{"puts(a)"}, "x\x12put\x07y\r", []string{"xy"}},
+		{"reverse search backspace", []string{"abc", "abd"}, "\x12abd\x7fc\r", []string{"abc"}},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		e := newLineEditor(strings.NewReader(tt.keys), &out, tt.history, complete)
+		lines := []string{}
+		for {
+			line, err := e.readLine(PROMPT)
+			if err != nil {
+				if err != io.EOF {
+					t.Errorf("%s: unexpected error %v", tt.name, err)
+				}
+				break
+			}
+			lines = append(lines, line)
+		}
+		if !reflect.DeepEqual(lines, tt.expected) {
+			t.Errorf("%s: lines wrong. expected=%q got=%q", tt.name, tt.expected, lines)
+		}
+	}
+}
+
+func TestLineEditorInterruptAndEOF(t *testing.T) {
+	var out bytes.Buffer
+	e := newLineEditor(strings.NewReader("abc\x03d\x04\x04\r\x04"), &out, nil, nil)
+
+	if _, err := e.readLine(PROMPT); err != errInterrupted {
+		t.Fatalf("Ctrl-C: expected errInterrupted, got %v", err)
+	}
+	//Ctrl-Dは行が空でなければ1文字消すだけ
+	line, err := e.readLine(PROMPT)
+	if err != nil || line != "d" {
+		t.Fatalf("Ctrl-D on non-empty line: got %q, %v", line, err)
+	}
+	if _, err := e.readLine(PROMPT); err != io.EOF {
+		t.Fatalf("Ctrl-D on empty line: expected io.EOF, got %v", err)
+	}
+	if !reflect.DeepEqual(e.history, []string{"d"}) {
+		t.Errorf("history wrong. got=%q", e.history)
+	}
+}
+
+func TestLineEditorListsCandidates(t *testing.T) {
+	var out bytes.Buffer
+	complete := func(prefix string) []string {
+		return sortedCandidates(prefix, []string{"len", "let"})
+	}
+	e := newLineEditor(strings.NewReader("le\t\r"), &out, nil, complete)
+	if _, err := e.readLine(PROMPT); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "\r\nlen  let\r\n") {
+		t.Errorf("candidates are not listed. got=%q", out.String())
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{">> ", 3},
+		{"名前", 4},
+		{"aｂc", 4},
+		{"", 0},
+	}
+
+	for _, tt := range tests {
+		if got := displayWidth(tt.input); got != tt.expected {
+			t.Errorf("displayWidth(%q) wrong. expected=%d got=%d", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestSessionCompletions(t *testing.T) {
+	s := newSession(ioutil.Discard)
+	s.eval("let length = 1; let unless = macro(c, x) { x };")
+
+	tests := []struct {
+		prefix   string
+		expected []string
+	}{
+		{"le", []string{"len", "length", "let"}},
+		{"un", []string{"unless"}},
+		{"pu", []string{"push", "puts"}},
+		{"zz", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := s.completions(tt.prefix); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("completions(%q) wrong. expected=%q got=%q", tt.prefix, tt.expected, got)
+		}
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, historyFileName)
+
+	if history := loadHistory(path); len(history) != 0 {
+		t.Errorf("missing file should give no history. got=%q", history)
+	}
+
+	for i := 0; i < historyLimit+2; i++ {
+		appendHistory(path, "line")
+	}
+	appendHistory(path, "last")
+	appendHistory(path, "multi\nline")
+
+	history := loadHistory(path)
+	if len(history) != historyLimit {
+		t.Fatalf("history length wrong. expected=%d got=%d", historyLimit, len(history))
+	}
+	if history[len(history)-1] != "last" {
+		t.Errorf("last entry wrong. got=%q", history[len(history)-1])
+	}
+
+	//ファイル自体も新しいほうのhistoryLimit行だけに切り詰められている
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Repeat("line\n", historyLimit-1) + "last\n"
+	if string(content) != expected {
+		t.Errorf("history file wrong. expected %d lines ending with \"last\", got %d lines",
+			historyLimit, strings.Count(string(content), "\n"))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("history file mode wrong. got=%v", info.Mode().Perm())
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("temporary files left in %s: %d files", dir, len(files))
+	}
+}
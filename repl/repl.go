@@ -1,42 +1,88 @@
 package repl
 
 import (
-	"bufio"
-	"fmt"
 	"interpreter-go/evaluator"
 	"interpreter-go/lexer"
+	"interpreter-go/object"
 	"interpreter-go/parser"
+	"interpreter-go/token"
 	"io"
 )
 
 const PROMPT = ">> "
 
+//inとoutが端末なら行編集と履歴を使い、そうでなければ1行ずつそのまま読む
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+	s := newSession(out)
+	reader := newLineReader(in, out, s)
 
 	for {
-		fmt.Print(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		input, ok := readInput(reader)
+		if !ok {
 			return
 		}
 
-		line := scanner.Text()
-		l := lexer.New(line)
-		parser := parser.New(l)
-		program := parser.ParseProgram()
-
-		if len(parser.Errors()) != 0 {
-			printParseErrors(out, parser.Errors())
+		if isCommand(input) {
+			s.runCommand(input)
 			continue
 		}
+		s.eval(input)
+	}
+}
 
-		obj := evaluator.Eval(program)
-		if obj != nil {
-			io.WriteString(out, obj.Inspect())
-			io.WriteString(out, "\n")
-		}
+//REPLを終了するか:resetするまで、定義した変数とマクロを保持する
+type session struct {
+	env      *object.Environment
+	macroEnv *object.Environment
+	out      io.Writer
+}
+
+func newSession(out io.Writer) *session {
+	s := &session{out: out}
+	s.reset()
+	return s
+}
+
+func (s *session) reset() {
+	s.env = object.NewEnvironment()
+	s.macroEnv = object.NewEnvironment()
+}
+
+func (s *session) eval(input string) {
+	l := lexer.New(input)
+	parser := parser.New(l)
+	program := parser.ParseProgram()
+
+	if len(parser.Errors()) != 0 {
+		printParseErrors(s.out, parser.Errors())
+		return
+	}
+
+	evaluator.DefineMacros(program, s.macroEnv)
+	expanded, err := evaluator.ExpandMacros(program, s.macroEnv)
+	if err != nil {
+		io.WriteString(s.out, "macro error: "+err.Error()+"\n")
+		return
+	}
+
+	obj := evaluator.Eval(expanded, s.env)
+	if obj == nil {
+		return
 	}
+	out, errObj := object.InspectSafely(obj)
+	if errObj != nil {
+		obj, out = errObj, errObj.Inspect()
+	}
+	io.WriteString(s.out, out)
+	io.WriteString(s.out, "\n")
+	if errObj, ok := obj.(*object.Error); ok && errObj.Stack != "" {
+		io.WriteString(s.out, errObj.Stack)
+	}
+}
+
+//キーワード、組み込み関数、セッションで束縛した名前から補完する
+func (s *session) completions(prefix string) []string {
+	return sortedCandidates(prefix, token.Keywords(), object.Builtins.Names(), s.env.Names(), s.macroEnv.Names())
 }
 
 func printParseErrors(out io.Writer, errors []string) {
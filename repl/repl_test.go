@@ -0,0 +1,116 @@
+package repl
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"let x = 1;", false},
+		{"let f = fn(x) {", true},
+		{"let f = fn(x) {\n  x + 1\n}", false},
+		{"add(1,", true},
+		{"[1, 2", true},
+		{`{"a":`, true},
+		{"1 +", true},
+		{"x &&", true},
+		{"let x =", true},
+		{"if (x) { 1 } else", true},
+		{`"unterminated`, true},
+		{"\"line\nbreak\"", false},
+		{`"bad\q" + 1`, false},
+		{"/* comment", true},
+		{"/* comment */ 1", false},
+		{"1 // trailing +", false},
+		{"}", false},
+		{"fn() { 1 }}", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := isIncomplete(tt.input); got != tt.expected {
+			t.Errorf("isIncomplete(%q) wrong. expected=%t got=%t", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestStartMultiLineInput(t *testing.T) {
+	input := "let add = fn(a, b) {\n  a +\n    b\n};\nadd(1,\n 2)\n\"multi\nline\"\nadd(1,"
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	expected := ">> .. .. .. >> .. 3\n>> .. multi\nline\n>> .. " +
+		"Woops! we ran into some monkey business here!\nparse error:\n\t1:7: no prefix parse function for EOF found\n>> "
+	if out.String() != expected {
+		t.Errorf("output wrong. expected=%q got=%q", expected, out.String())
+	}
+}
+
+func TestCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "repl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	script := filepath.Join(dir, "lib.mk")
+	if err := ioutil.WriteFile(script, []byte("#!/usr/bin/env monkey\nlet double = fn(x) {\n  x * 2\n};\ndouble(2)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let b = 2; let a = 1;\n:env", "a = 1\nb = 2\n"},
+		{"let unless = macro(c, x) { x };\n:env", "unless = macro\n"},
+		{"let a = 1;\n:reset\n:env\na", "ERROR: identifier not found: a\n"},
+		{":tokens let x = \"a\"", "1:1\tLET\t\"let\"\n1:5\tIDENT\t\"x\"\n1:7\t=\t\"=\"\n1:9\tSTRING\t\"a\"\n"},
+		{":ast -x + 1", "ExpressionStatement\n" +
+			"  Expression: InfixExpression Operator=\"+\"\n" +
+			"    Left: PrefixExpression Operator=\"-\"\n" +
+			"      Right: Identifier Value=\"x\"\n" +
+			"    Right: IntegerLiteral Value=1\n"},
+		{":ast let f = fn(x) { }", "LetStatementNode\n" +
+			"  Name: Identifier Value=\"f\"\n" +
+			"  Value: FunctionLiteral Name=\"f\"\n" +
+			"    Parameters[0]: Identifier Value=\"x\"\n" +
+			"    Body: BlockStatement\n"},
+		{":ast let = 1", "Woops! we ran into some monkey business here!\nparse error:\n\t1:5: expected IDENT\n"},
+		{":load " + script + "\ndouble(21)", "4\n42\n"},
+		{":load", "usage: :load <file>\n"},
+		{":quit", "unknown command: :quit (type :help for a list of commands)\n"},
+		{"  :reset  ", ""},
+		{"[if (true) {}]\n1", "[null]\n1\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		Start(strings.NewReader(tt.input), &out)
+		if got := withoutPrompts(out.String()); got != tt.expected {
+			t.Errorf("%q: output wrong. expected=%q got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestHelpListsAllCommands(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader(":help"), &out)
+
+	for _, c := range commands {
+		if !strings.Contains(out.String(), ":"+c.name) {
+			t.Errorf("help does not mention :%s. got=%q", c.name, out.String())
+		}
+	}
+}
+
+func withoutPrompts(output string) string {
+	return strings.ReplaceAll(output, PROMPT, "")
+}
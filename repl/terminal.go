@@ -0,0 +1,129 @@
+package repl
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+//履歴ファイルに残す行数
+const historyLimit = 1000
+
+//ホームディレクトリの下の履歴ファイル
+const historyFileName = ".monkey_history"
+
+func newLineReader(in io.Reader, out io.Writer, s *session) lineReader {
+	inFile, inOK := in.(*os.File)
+	outFile, outOK := out.(*os.File)
+	if !inOK || !outOK || !term.IsTerminal(int(inFile.Fd())) || !term.IsTerminal(int(outFile.Fd())) {
+		return &scannerReader{scanner: bufio.NewScanner(in), out: out}
+	}
+
+	path := historyPath()
+	return &terminalReader{
+		fd:          int(inFile.Fd()),
+		editor:      newLineEditor(in, out, loadHistory(path), s.completions),
+		historyFile: path,
+	}
+}
+
+//1行読むあいだだけ端末をrawモードにする。評価結果の出力は通常のモードで行う
+type terminalReader struct {
+	fd          int
+	editor      *lineEditor
+	historyFile string //空なら履歴を保存しない
+}
+
+func (t *terminalReader) readLine(prompt string) (string, error) {
+	state, err := term.MakeRaw(t.fd)
+	if err != nil {
+		return "", err
+	}
+	added := len(t.editor.history)
+	line, err := t.editor.readLine(prompt)
+	term.Restore(t.fd, state)
+
+	if len(t.editor.history) > added {
+		appendHistory(t.historyFile, line)
+	}
+	return line, err
+}
+
+func historyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, historyFileName)
+}
+
+//履歴ファイルがなくてもエラーにはしない
+func loadHistory(path string) []string {
+	history := readHistoryFile(path)
+	if len(history) > historyLimit {
+		history = history[len(history)-historyLimit:]
+	}
+	return history
+}
+
+func readHistoryFile(path string) []string {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	history := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		history = append(history, scanner.Text())
+	}
+	return history
+}
+
+//履歴の保存に失敗してもREPLは続ける。
+//ファイルがhistoryLimit行を超えたら、新しいほうのhistoryLimit行だけを残して書き直す
+func appendHistory(path, line string) {
+	if path == "" || strings.ContainsRune(line, '\n') {
+		return
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	_, err = io.WriteString(f, line+"\n")
+	f.Close()
+	if err != nil {
+		return
+	}
+
+	if history := readHistoryFile(path); len(history) > historyLimit {
+		rewriteHistory(path, history[len(history)-historyLimit:])
+	}
+}
+
+//書き直している途中で止まっても元の履歴が消えないように、別のファイルに書いてから置き換える
+func rewriteHistory(path string, history []string) {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), historyFileName)
+	if err != nil {
+		return
+	}
+	_, err = io.WriteString(tmp, strings.Join(history, "\n")+"\n")
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
@@ -1,45 +1,93 @@
 package token
 
+import (
+	"fmt"
+	"sort"
+)
+
 type TokenType string
 
+//Posはトークンの先頭、Endはトークンの直後の位置
 type Token struct {
-	Type TokenType
+	Type    TokenType
 	Literal string
+	Pos     Position
+	End     Position
+	Message string //ILEEGALのとき、字句解析のエラーの理由。想定外の文字なら空
+}
+
+//LineとColumnは1から数える。Offsetは入力の先頭からのバイト数
+type Position struct {
+	Line   int
+	Column int
+	Offset int
+}
+
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 const (
 	ILEEGAL = "ILEEGAL"
 	EOF     = "EOF"
+	//lexer.NewWithCommentsで作ったLexerだけが返す
+	COMMENT = "COMMENT"
 )
 
 const (
 	IDENT = "IDENT"
 	INT = "INT"
+	FLOAT = "FLOAT"
+	STRING = "STRING"
 )
 
 const (
 	ASSIGN = "="
+	PLUS_ASSIGN = "+="
+	MINUS_ASSIGN = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN = "/="
 	PLUS = "+"
 	MINUS = "-"
 	SLASH = "/"
 	ASTERISK = "*"
+	PERCENT = "%"
 
 	LT = "<"
 	RT = ">"
+	LT_EQ = "<="
+	RT_EQ = ">="
 	BANG ="!"
 
 	EQ = "=="
 	NOT_EQ = "!="
+
+	AND = "&&"
+	OR = "||"
+
+	BIT_AND = "&"
+	BIT_OR = "|"
+	BIT_XOR = "^"
+	BIT_NOT = "~"
+	SHIFT_LEFT = "<<"
+	SHIFT_RIGHT = ">>"
 )
 
 const (
 	COMMA = ","
 	SEMICOLON = ";"
+	COLON = ":"
 
 	LPAREN = "("
 	RPAREN = ")"
 	LBRACE = "{"
 	RBRACE = "}"
+	LBRACKET = "["
+	RBRACKET = "]"
 )
 
 const (
@@ -50,6 +98,12 @@ const (
 	IF = "IF"
 	ELSE = "ELSE"
 	RETURN = "RETURN"
+	MACRO = "MACRO"
+	WHILE = "WHILE"
+	FOR = "FOR"
+	IN = "IN"
+	BREAK = "BREAK"
+	CONTINUE = "CONTINUE"
 )
 
 var keywords = map[string]TokenType{
@@ -60,6 +114,12 @@ var keywords = map[string]TokenType{
 	"if": IF,
 	"else": ELSE,
 	"return": RETURN,
+	"macro": MACRO,
+	"while": WHILE,
+	"for": FOR,
+	"in": IN,
+	"break": BREAK,
+	"continue": CONTINUE,
 }
 
 func LookUpIdent(ident string) TokenType{
@@ -68,3 +128,13 @@ func LookUpIdent(ident string) TokenType{
 	}
 	return IDENT
 }
+
+//キーワードを辞書順で返す。REPLの補完に使う
+func Keywords() []string {
+	names := make([]string, 0, len(keywords))
+	for name := range keywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,21 @@
+package vm
+
+import (
+	"interpreter-go/code"
+	"interpreter-go/object"
+)
+
+//関数呼び出しごとの実行状態。basePointerから先がローカル変数の領域
+type Frame struct {
+	cl          *object.Closure
+	ip          int
+	basePointer int
+}
+
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{cl: cl, ip: -1, basePointer: basePointer}
+}
+
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
@@ -0,0 +1,18 @@
+package vm
+
+import (
+	"fmt"
+	"interpreter-go/object"
+)
+
+//for inのループ中だけ使う。コンパイラが名前のない変数に入れる
+type iterator struct {
+	elements []object.Object
+	index    int //次に返す要素
+}
+
+func (it *iterator) Inspect() string {
+	return fmt.Sprintf("iterator[%d/%d]", it.index, len(it.elements))
+}
+
+func (it *iterator) Type() object.ObjectType { return "ITERATOR" }
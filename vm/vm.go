@@ -0,0 +1,751 @@
+package vm
+
+import (
+	"fmt"
+	"interpreter-go/code"
+	"interpreter-go/compiler"
+	"interpreter-go/object"
+	"math"
+)
+
+const StackSize = 2048
+const GlobalsSize = 65536
+const MaxFrames = 1024
+
+var (
+	True  = &object.Boolean{Value: true}
+	False = &object.Boolean{Value: false}
+	Null  = &object.Null{}
+)
+
+type VM struct {
+	constants []object.Object
+
+	stack []object.Object
+	sp    int //次に積む位置。stack[sp-1]が先頭
+
+	globals     []object.Object
+	globalNames []string
+
+	frames      []*Frame
+	framesIndex int
+
+	lastPopped object.Object //最後の式文の値。letやループの後はnilか、NULLになる
+
+	overflowMode object.OverflowMode
+}
+
+func New(bytecode *compiler.Bytecode) *VM {
+	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
+	mainClosure := &object.Closure{Fn: mainFn}
+	mainFrame := NewFrame(mainClosure, 0)
+
+	frames := make([]*Frame, MaxFrames)
+	frames[0] = mainFrame
+
+	return &VM{
+		constants:   bytecode.Constants,
+		stack:       make([]object.Object, StackSize),
+		sp:          0,
+		globals:     make([]object.Object, GlobalsSize),
+		globalNames: bytecode.GlobalNames,
+		frames:      frames,
+		framesIndex: 1,
+	}
+}
+
+//整数の演算結果がint64に収まらないときの扱いを変える。既定はPromoteOnOverflow
+func (vm *VM) SetOverflowMode(mode object.OverflowMode) {
+	vm.overflowMode = mode
+}
+
+//REPLのように、前回の実行で定義したグローバル変数を引き継ぐ
+func NewWithGlobalsStore(bytecode *compiler.Bytecode, s []object.Object) *VM {
+	vm := New(bytecode)
+	vm.globals = s
+	return vm
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) error {
+	if vm.framesIndex >= MaxFrames {
+		return fmt.Errorf("stack overflow")
+	}
+	vm.frames[vm.framesIndex] = f
+	vm.framesIndex++
+	return nil
+}
+
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
+
+//最後に式文でpopした値。letやループなど、値を持たない文の中でpopした値は含めない
+func (vm *VM) LastPoppedStackElem() object.Object {
+	return vm.lastPopped
+}
+
+//実行中にGoのpanicが起きたときは、*object.Errorをerrorとして返す
+func (vm *VM) Run() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = object.NewPanicError(r)
+		}
+	}()
+
+	var ip int
+	var ins code.Instructions
+	var op code.Opcode
+
+	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		vm.currentFrame().ip++
+
+		ip = vm.currentFrame().ip
+		ins = vm.currentFrame().Instructions()
+		op = code.Opcode(ins[ip])
+
+		switch op {
+		case code.OpConstant:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			constant := vm.constants[constIndex]
+			//int64に収まらない定数は整数リテラルからしか作られない
+			if big, ok := constant.(*object.BigInt); ok {
+				if err := object.CheckBigIntegerLiteral(big.Value, vm.overflowMode); err != nil {
+					return err
+				}
+			}
+			if err := vm.push(constant); err != nil {
+				return err
+			}
+
+		case code.OpPop:
+			vm.lastPopped = vm.pop()
+
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod,
+			code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShiftLeft, code.OpShiftRight,
+			code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpLessThan,
+			code.OpGreaterEqual, code.OpLessEqual:
+			if err := vm.executeBinaryOperation(op); err != nil {
+				return err
+			}
+
+		case code.OpTrue:
+			if err := vm.push(True); err != nil {
+				return err
+			}
+
+		case code.OpFalse:
+			if err := vm.push(False); err != nil {
+				return err
+			}
+
+		case code.OpNull:
+			if err := vm.push(Null); err != nil {
+				return err
+			}
+
+		case code.OpBang:
+			if err := vm.executeBangOperator(); err != nil {
+				return err
+			}
+
+		case code.OpMinus:
+			if err := vm.executeMinusOperator(); err != nil {
+				return err
+			}
+
+		case code.OpBitNot:
+			result, err := object.BitNotInteger(vm.pop())
+			if err != nil {
+				return err
+			}
+			if err := vm.push(result); err != nil {
+				return err
+			}
+
+		case code.OpJump:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip = pos - 1
+
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			condition := vm.pop()
+			if !isTruthy(condition) {
+				vm.currentFrame().ip = pos - 1
+			}
+
+		case code.OpSetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			vm.globals[globalIndex] = vm.pop()
+			//評価器と同じく、トップレベルのletは結果を残さない
+			vm.lastPopped = nil
+
+		case code.OpGetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			if err := vm.pushVariable(vm.globals[globalIndex], vm.globalNames, int(globalIndex)); err != nil {
+				return err
+			}
+
+		case code.OpSetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			vm.stack[frame.basePointer+int(localIndex)] = vm.pop()
+
+		case code.OpGetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			value := vm.stack[frame.basePointer+int(localIndex)]
+			if err := vm.pushVariable(value, frame.cl.Fn.LocalNames, int(localIndex)); err != nil {
+				return err
+			}
+
+		case code.OpGetBuiltin:
+			builtinIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			builtin, ok := object.Builtins.Get(int(builtinIndex))
+			if !ok {
+				return fmt.Errorf("builtin %d not found", builtinIndex)
+			}
+			if err := vm.push(builtin); err != nil {
+				return err
+			}
+
+		case code.OpGetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			currentClosure := vm.currentFrame().cl
+			value := currentClosure.Free[freeIndex].Value
+			if err := vm.pushVariable(value, currentClosure.Fn.FreeNames, int(freeIndex)); err != nil {
+				return err
+			}
+
+		case code.OpSetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			vm.currentFrame().cl.Free[freeIndex].Value = vm.pop()
+
+		case code.OpFreeCell:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			if err := vm.push(vm.currentFrame().cl.Free[freeIndex]); err != nil {
+				return err
+			}
+
+		case code.OpGetCell:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			cell := vm.stack[frame.basePointer+int(localIndex)].(*object.Cell)
+			if err := vm.pushVariable(cell.Value, frame.cl.Fn.LocalNames, int(localIndex)); err != nil {
+				return err
+			}
+
+		case code.OpSetCell:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			cell := vm.stack[frame.basePointer+int(localIndex)].(*object.Cell)
+			cell.Value = vm.pop()
+
+		case code.OpArray:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			array := vm.buildArray(vm.sp-numElements, vm.sp)
+			vm.sp = vm.sp - numElements
+
+			if err := vm.push(array); err != nil {
+				return err
+			}
+
+		case code.OpHash:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			hash, err := vm.buildHash(vm.sp-numElements, vm.sp)
+			if err != nil {
+				return err
+			}
+			vm.sp = vm.sp - numElements
+
+			if err := vm.push(hash); err != nil {
+				return err
+			}
+
+		case code.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+
+			if err := vm.executeIndexExpression(left, index); err != nil {
+				return err
+			}
+
+		case code.OpCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			if err := vm.executeCall(int(numArgs)); err != nil {
+				return err
+			}
+
+		case code.OpReturnValue:
+			returnValue := vm.pop()
+
+			//トップレベルのreturnは、その値でプログラムを終える
+			if vm.framesIndex == 1 {
+				vm.lastPopped = returnValue
+				return nil
+			}
+
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+
+			if err := vm.push(returnValue); err != nil {
+				return err
+			}
+
+		case code.OpReturn:
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+
+			if err := vm.push(Null); err != nil {
+				return err
+			}
+
+		case code.OpIter:
+			iterable := vm.pop()
+
+			elements, ok := object.Elements(iterable)
+			if !ok {
+				return fmt.Errorf("cannot iterate over %s", iterable.Type())
+			}
+			if err := vm.push(&iterator{elements: elements}); err != nil {
+				return err
+			}
+
+		case code.OpIterNext:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			iter := vm.pop().(*iterator)
+			if iter.index >= len(iter.elements) {
+				vm.currentFrame().ip = pos - 1
+			} else {
+				iter.index++
+				if err := vm.push(iter.elements[iter.index-1]); err != nil {
+					return err
+				}
+			}
+
+		case code.OpSetIndex:
+			value := vm.pop()
+			index := vm.pop()
+			left := vm.pop()
+
+			if err := object.SetIndex(left, index, value); err != nil {
+				return err
+			}
+			if err := vm.push(value); err != nil {
+				return err
+			}
+
+		case code.OpStackPointer:
+			if err := vm.push(&object.Integer{Value: int64(vm.sp)}); err != nil {
+				return err
+			}
+
+		case code.OpRestoreStack:
+			vm.sp = int(vm.pop().(*object.Integer).Value)
+
+		case code.OpDup2:
+			if err := vm.push(vm.stack[vm.sp-2]); err != nil {
+				return err
+			}
+			if err := vm.push(vm.stack[vm.sp-2]); err != nil {
+				return err
+			}
+
+		case code.OpClosure:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			numFree := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+
+			if err := vm.pushClosure(int(constIndex), int(numFree)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (vm *VM) push(o object.Object) error {
+	if vm.sp >= StackSize {
+		return fmt.Errorf("stack overflow")
+	}
+
+	vm.stack[vm.sp] = o
+	vm.sp++
+
+	return nil
+}
+
+//まだ値を入れていない変数は、評価器で束縛されていない名前を読んだときと同じエラーにする
+func (vm *VM) pushVariable(value object.Object, names []string, index int) error {
+	if value == nil {
+		name := ""
+		if index < len(names) {
+			name = names[index]
+		}
+		return fmt.Errorf("identifier not found: %s", name)
+	}
+	return vm.push(value)
+}
+
+func (vm *VM) pop() object.Object {
+	o := vm.stack[vm.sp-1]
+	vm.sp--
+	return o
+}
+
+//評価器のevalInfixExpressionと同じ順番で判定して、同じエラーを返す
+func (vm *VM) executeBinaryOperation(op code.Opcode) error {
+	right := vm.pop()
+	left := vm.pop()
+
+	leftType := left.Type()
+	rightType := right.Type()
+
+	switch {
+	case leftType == object.INTEGER_OBJ && rightType == object.INTEGER_OBJ:
+		return vm.executeBinaryIntegerOperation(op, left, right)
+	case isNumber(left) && isNumber(right):
+		return vm.executeBinaryFloatOperation(op, left, right)
+	case leftType == object.STRING_OBJ && rightType == object.STRING_OBJ:
+		return vm.executeBinaryStringOperation(op, left, right)
+	case op == code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(object.Equal(left, right)))
+	case op == code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(!object.Equal(left, right)))
+	case leftType != rightType:
+		return fmt.Errorf("type mismatch: %s %s %s", leftType, operators[op], rightType)
+	default:
+		return fmt.Errorf("unknown operator: %s %s %s", leftType, operators[op], rightType)
+	}
+}
+
+var operators = map[code.Opcode]string{
+	code.OpAdd:          "+",
+	code.OpSub:          "-",
+	code.OpMul:          "*",
+	code.OpDiv:          "/",
+	code.OpMod:          "%",
+	code.OpBitAnd:       "&",
+	code.OpBitOr:        "|",
+	code.OpBitXor:       "^",
+	code.OpShiftLeft:    "<<",
+	code.OpShiftRight:   ">>",
+	code.OpEqual:        "==",
+	code.OpNotEqual:     "!=",
+	code.OpGreaterThan:  ">",
+	code.OpLessThan:     "<",
+	code.OpGreaterEqual: ">=",
+	code.OpLessEqual:    "<=",
+}
+
+func (vm *VM) executeBinaryIntegerOperation(op code.Opcode, left, right object.Object) error {
+	switch op {
+	case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod,
+		code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShiftLeft, code.OpShiftRight:
+		result, err := object.IntegerArithmetic(operators[op], left, right, vm.overflowMode)
+		if err != nil {
+			return err
+		}
+		return vm.push(result)
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(object.CompareIntegers(left, right) == 0))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(object.CompareIntegers(left, right) != 0))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(object.CompareIntegers(left, right) > 0))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(object.CompareIntegers(left, right) < 0))
+	case code.OpGreaterEqual:
+		return vm.push(nativeBoolToBooleanObject(object.CompareIntegers(left, right) >= 0))
+	case code.OpLessEqual:
+		return vm.push(nativeBoolToBooleanObject(object.CompareIntegers(left, right) <= 0))
+	default:
+		return fmt.Errorf("unknown operator: %s %s %s", left.Type(), operators[op], right.Type())
+	}
+}
+
+//片方が整数のときは浮動小数点数に変換してから計算する
+func (vm *VM) executeBinaryFloatOperation(op code.Opcode, left, right object.Object) error {
+	leftValue := toFloat(left)
+	rightValue := toFloat(right)
+
+	switch op {
+	case code.OpAdd:
+		return vm.push(&object.Float{Value: leftValue + rightValue})
+	case code.OpSub:
+		return vm.push(&object.Float{Value: leftValue - rightValue})
+	case code.OpMul:
+		return vm.push(&object.Float{Value: leftValue * rightValue})
+	case code.OpDiv:
+		return vm.push(&object.Float{Value: leftValue / rightValue})
+	case code.OpMod:
+		return vm.push(&object.Float{Value: math.Mod(leftValue, rightValue)})
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue == rightValue))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue != rightValue))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue < rightValue))
+	case code.OpGreaterEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue >= rightValue))
+	case code.OpLessEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue <= rightValue))
+	default:
+		return fmt.Errorf("unknown operator: %s %s %s", left.Type(), operators[op], right.Type())
+	}
+}
+
+func (vm *VM) executeBinaryStringOperation(op code.Opcode, left, right object.Object) error {
+	leftValue := left.(*object.String).Value
+	rightValue := right.(*object.String).Value
+
+	switch op {
+	case code.OpAdd:
+		return vm.push(&object.String{Value: leftValue + rightValue})
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue == rightValue))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue != rightValue))
+	default:
+		return fmt.Errorf("unknown operator: %s %s %s", left.Type(), operators[op], right.Type())
+	}
+}
+
+func (vm *VM) executeBangOperator() error {
+	operand := vm.pop()
+	return vm.push(nativeBoolToBooleanObject(!isTruthy(operand)))
+}
+
+func (vm *VM) executeMinusOperator() error {
+	operand := vm.pop()
+
+	switch operand := operand.(type) {
+	case *object.Integer, *object.BigInt:
+		result, err := object.NegateInteger(operand, vm.overflowMode)
+		if err != nil {
+			return err
+		}
+		return vm.push(result)
+	case *object.Float:
+		return vm.push(&object.Float{Value: -operand.Value})
+	default:
+		return fmt.Errorf("unknown operator: -%s", operand.Type())
+	}
+}
+
+func (vm *VM) buildArray(startIndex, endIndex int) object.Object {
+	elements := make([]object.Object, endIndex-startIndex)
+
+	for i := startIndex; i < endIndex; i++ {
+		elements[i-startIndex] = vm.stack[i]
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, error) {
+	hashedPairs := make(map[object.HashKey]object.HashPair)
+
+	for i := startIndex; i < endIndex; i += 2 {
+		key := vm.stack[i]
+		value := vm.stack[i+1]
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+		}
+
+		hashedPairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: value}
+	}
+
+	return &object.Hash{Pairs: hashedPairs}, nil
+}
+
+func (vm *VM) executeIndexExpression(left, index object.Object) error {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		return vm.executeArrayIndex(left, index)
+	case left.Type() == object.HASH_OBJ:
+		return vm.executeHashIndex(left, index)
+	default:
+		return fmt.Errorf("index operator not supported: %s", left.Type())
+	}
+}
+
+//負のインデックスは末尾から数える。範囲外はNullを積む
+func (vm *VM) executeArrayIndex(array, index object.Object) error {
+	elements := array.(*object.Array).Elements
+	integer, ok := index.(*object.Integer)
+	if !ok {
+		return vm.push(Null) //BigIntはどの配列に対しても範囲外
+	}
+	i := integer.Value
+	length := int64(len(elements))
+
+	if i < 0 {
+		i += length
+	}
+	if i < 0 || i >= length {
+		return vm.push(Null)
+	}
+
+	return vm.push(elements[i])
+}
+
+func (vm *VM) executeHashIndex(hash, index object.Object) error {
+	hashObject := hash.(*object.Hash)
+
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return fmt.Errorf("unusable as hash key: %s", index.Type())
+	}
+
+	pair, ok := hashObject.Pairs[key.HashKey()]
+	if !ok {
+		return vm.push(Null)
+	}
+
+	return vm.push(pair.Value)
+}
+
+func (vm *VM) executeCall(numArgs int) error {
+	callee := vm.stack[vm.sp-1-numArgs]
+	switch callee := callee.(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
+	case *object.Builtin:
+		return vm.callBuiltin(callee, numArgs)
+	default:
+		return fmt.Errorf("not a function: %s", callee.Type())
+	}
+}
+
+func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
+	if numArgs != cl.Fn.NumParameters {
+		return fmt.Errorf("wrong number of arguments: want=%d, got=%d", cl.Fn.NumParameters, numArgs)
+	}
+
+	frame := NewFrame(cl, vm.sp-numArgs)
+	if err := vm.pushFrame(frame); err != nil {
+		return err
+	}
+
+	if frame.basePointer+cl.Fn.NumLocals >= StackSize {
+		return fmt.Errorf("stack overflow")
+	}
+	vm.sp = frame.basePointer + cl.Fn.NumLocals
+
+	//引数以外のスロットには前の呼び出しの値が残っているので、値のない状態に戻す
+	for i := cl.Fn.NumParameters; i < cl.Fn.NumLocals; i++ {
+		vm.stack[frame.basePointer+i] = nil
+	}
+
+	//内側の関数が捕まえるローカル変数は、呼び出すたびに新しいセルに入れる
+	for _, i := range cl.Fn.Cells {
+		slot := frame.basePointer + i
+		vm.stack[slot] = &object.Cell{Value: vm.stack[slot]}
+	}
+
+	return nil
+}
+
+//組み込み関数が返したエラーは、評価器と同じように実行を止める
+func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
+	args := vm.stack[vm.sp-numArgs : vm.sp]
+
+	result := builtin.Call(args...)
+	vm.sp = vm.sp - numArgs - 1
+
+	if errObj, ok := result.(*object.Error); ok {
+		return fmt.Errorf("%s", errObj.Message)
+	}
+	if result == nil {
+		return vm.push(Null)
+	}
+	return vm.push(result)
+}
+
+func (vm *VM) pushClosure(constIndex int, numFree int) error {
+	constant := vm.constants[constIndex]
+	function, ok := constant.(*object.CompiledFunction)
+	if !ok {
+		return fmt.Errorf("not a function: %+v", constant)
+	}
+
+	free := make([]*object.Cell, numFree)
+	for i := 0; i < numFree; i++ {
+		free[i] = vm.stack[vm.sp-numFree+i].(*object.Cell)
+	}
+	vm.sp = vm.sp - numFree
+
+	closure := &object.Closure{Fn: function, Free: free}
+	return vm.push(closure)
+}
+
+func nativeBoolToBooleanObject(input bool) *object.Boolean {
+	if input {
+		return True
+	}
+	return False
+}
+
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+func toFloat(obj object.Object) float64 {
+	if float, ok := obj.(*object.Float); ok {
+		return float.Value
+	}
+	return object.IntegerToFloat(obj)
+}
+
+func isTruthy(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value
+	case *object.Null:
+		return false
+	default:
+		return true
+	}
+}
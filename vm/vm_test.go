@@ -0,0 +1,113 @@
+package vm
+
+import (
+	"interpreter-go/code"
+	"interpreter-go/compiler"
+	"interpreter-go/conformance"
+	"interpreter-go/lexer"
+	"interpreter-go/object"
+	"interpreter-go/parser"
+	"strings"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, testRun)
+}
+
+func TestGlobalsStore(t *testing.T) {
+	globals := make([]object.Object, GlobalsSize)
+	symbolTable := compiler.NewSymbolTable()
+	for i, name := range object.Builtins.Names() {
+		symbolTable.DefineBuiltin(i, name)
+	}
+	constants := []object.Object{}
+
+	inputs := []string{"let a = 1;", "let f = fn(x) { x + a };", "f(2)"}
+
+	var result object.Object
+	for _, input := range inputs {
+		p := parser.New(lexer.New(input))
+		program := p.ParseProgram()
+
+		comp := compiler.NewWithState(symbolTable, constants)
+		if err := comp.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		bytecode := comp.Bytecode()
+		constants = bytecode.Constants
+
+		machine := NewWithGlobalsStore(bytecode, globals)
+		if err := machine.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+		result = machine.LastPoppedStackElem()
+	}
+
+	integer, ok := result.(*object.Integer)
+	if !ok || integer.Value != 3 {
+		t.Errorf("wrong result. got=%T(%+v)", result, result)
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	result := testRun("let f = fn(x) { f(x + 1) }; f(0);")
+
+	errObj, ok := result.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T(%+v)", result, result)
+	}
+	if errObj.Message != "stack overflow" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestRecoverFromPanic(t *testing.T) {
+	//存在しない定数を読ませて、VMの中でpanicを起こす
+	bytecode := &compiler.Bytecode{
+		Instructions: code.Make(code.OpConstant, 5),
+		Constants:    []object.Object{},
+	}
+
+	machine := New(bytecode)
+	err := machine.Run()
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+
+	errObj, ok := err.(*object.Error)
+	if !ok {
+		t.Fatalf("error is not *object.Error. got=%T(%+v)", err, err)
+	}
+	if !strings.HasPrefix(errObj.Message, "internal error: ") {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestErrorOnOverflow(t *testing.T) {
+	conformance.RunGroups(t, conformance.ErrorOnOverflowGroups, func(input string) object.Object {
+		return testRunWithMode(input, object.ErrorOnOverflow)
+	})
+}
+
+func testRun(input string) object.Object {
+	return testRunWithMode(input, object.PromoteOnOverflow)
+}
+
+func testRunWithMode(input string, mode object.OverflowMode) object.Object {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		return &object.Error{Message: err.Error()}
+	}
+
+	machine := New(comp.Bytecode())
+	machine.SetOverflowMode(mode)
+	if err := machine.Run(); err != nil {
+		return &object.Error{Message: err.Error()}
+	}
+
+	return machine.LastPoppedStackElem()
+}